@@ -0,0 +1,68 @@
+package liboc
+
+import (
+	"strings"
+
+	"github.com/sagernet/sing-box/experimental/clashapi"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+type ClashModeListener interface {
+	UpdateClashMode(newMode string)
+}
+
+// SetClashModeListener sets the listener notified whenever the clash mode
+// changes, including changes made through the clash API.
+func (s *BoxService) SetClashModeListener(listener ClashModeListener) {
+	s.clashModeListener = listener
+}
+
+func (s *BoxService) GetClashModeList() StringIterator {
+	return newIterator(s.current().clashServer.ModeList())
+}
+
+func (s *BoxService) GetClashMode() string {
+	return s.current().clashServer.Mode()
+}
+
+func (s *BoxService) SetClashMode(mode string) error {
+	clashServer, loaded := s.current().clashServer.(*clashapi.Server)
+	if !loaded {
+		return E.New("clash api not available")
+	}
+	if !common.Any(clashServer.ModeList(), func(it string) bool {
+		return strings.EqualFold(it, mode)
+	}) {
+		return E.New("unknown clash mode: ", mode)
+	}
+	clashServer.SetMode(mode)
+	return nil
+}
+
+// watchClashMode forwards mode updates of the current instance to the
+// command server and the clash mode listener until the instance is closed.
+func (s *BoxService) watchClashMode(state *serviceState) {
+	clashServer, loaded := state.clashServer.(*clashapi.Server)
+	if !loaded {
+		return
+	}
+	ctx := state.ctx
+	modeUpdate := make(chan struct{}, 1)
+	clashServer.SetModeUpdateHook(modeUpdate)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-modeUpdate:
+			}
+			if commandServer := s.commandServer; commandServer != nil {
+				commandServer.notifyModeUpdate()
+			}
+			if listener := s.clashModeListener; listener != nil {
+				listener.UpdateClashMode(clashServer.Mode())
+			}
+		}
+	}()
+}
@@ -0,0 +1,16 @@
+package liboc
+
+const (
+	CommandLog int32 = iota
+	CommandStatus
+	CommandServiceReload
+	CommandServiceClose
+	CommandCloseConnections
+	CommandGroup
+	CommandSelectOutbound
+	CommandClashMode
+	CommandSetClashMode
+	CommandConnections
+	CommandCloseConnection
+	CommandGetDeprecatedNotes
+)
@@ -0,0 +1,115 @@
+package liboc
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"time"
+
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/varbin"
+)
+
+func (c *CommandClient) handleGroupConn(conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		groups, err := readGroups(conn)
+		if err != nil {
+			c.handler.Disconnected(err.Error())
+			return
+		}
+		c.handler.WriteGroups(groups)
+	}
+}
+
+func (s *CommandServer) handleGroupConn(conn net.Conn) error {
+	var interval int64
+	err := binary.Read(conn, binary.BigEndian, &interval)
+	if err != nil {
+		return E.Cause(err, "read interval")
+	}
+	ticker := time.NewTicker(commandInterval(interval))
+	defer ticker.Stop()
+	ctx := connKeepAlive(conn)
+	writer := bufio.NewWriter(conn)
+	for {
+		service := s.service.Load()
+		if service != nil {
+			err = writeGroups(writer, service)
+		} else {
+			err = varbin.Write(writer, binary.BigEndian, []OutboundGroup{})
+		}
+		if err != nil {
+			return err
+		}
+		err = writer.Flush()
+		if err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.urlTestUpdate:
+		}
+	}
+}
+
+func readGroups(reader io.Reader) (OutboundGroupIterator, error) {
+	groups, err := varbin.ReadValue[[]*OutboundGroup](reader, binary.BigEndian)
+	if err != nil {
+		return nil, err
+	}
+	return newIterator(groups), nil
+}
+
+func writeGroups(writer io.Writer, boxService *BoxService) error {
+	groups := common.Filter(boxService.outboundGroups(), func(it OutboundGroup) bool {
+		return len(it.ItemList) >= 2
+	})
+	return varbin.Write(writer, binary.BigEndian, groups)
+}
+
+func (c *CommandClient) SelectOutbound(groupTag string, outboundTag string) error {
+	conn, err := c.directConnect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = binary.Write(conn, binary.BigEndian, uint8(CommandSelectOutbound))
+	if err != nil {
+		return err
+	}
+	err = varbin.Write(conn, binary.BigEndian, groupTag)
+	if err != nil {
+		return err
+	}
+	err = varbin.Write(conn, binary.BigEndian, outboundTag)
+	if err != nil {
+		return err
+	}
+	return readError(conn)
+}
+
+func (s *CommandServer) handleSelectOutbound(conn net.Conn) error {
+	groupTag, err := varbin.ReadValue[string](conn, binary.BigEndian)
+	if err != nil {
+		return err
+	}
+	outboundTag, err := varbin.ReadValue[string](conn, binary.BigEndian)
+	if err != nil {
+		return err
+	}
+	service := s.service.Load()
+	if service == nil {
+		return writeError(conn, E.New("service not ready"))
+	}
+	return writeError(conn, service.SelectOutbound(groupTag, outboundTag))
+}
@@ -0,0 +1,225 @@
+package liboc
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sagernet/sing-box/common/urltest"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/debug"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/observable"
+	"github.com/sagernet/sing/common/x/list"
+)
+
+type CommandServer struct {
+	listener net.Listener
+	handler  CommandServerHandler
+
+	access     sync.Mutex
+	savedLines list.List[string]
+	maxLines   int
+	subscriber *observable.Subscriber[string]
+	observer   *observable.Observer[string]
+
+	service       atomic.Pointer[BoxService]
+	serviceUpdate chan struct{}
+
+	// These channels only work with a single client.
+	urlTestUpdate chan struct{}
+	modeUpdate    chan struct{}
+	logReset      chan struct{}
+}
+
+type CommandServerHandler interface {
+	ServiceReload() error
+	PostServiceClose()
+}
+
+func NewCommandServer(handler CommandServerHandler, maxLines int32) *CommandServer {
+	server := &CommandServer{
+		handler:       handler,
+		maxLines:      int(maxLines),
+		subscriber:    observable.NewSubscriber[string](128),
+		urlTestUpdate: make(chan struct{}, 1),
+		modeUpdate:    make(chan struct{}, 1),
+		logReset:      make(chan struct{}, 1),
+		serviceUpdate: make(chan struct{}),
+	}
+	server.observer = observable.NewObserver[string](server.subscriber, 64)
+	return server
+}
+
+func (s *CommandServer) SetService(newService *BoxService) {
+	if newService != nil {
+		if newService.commandServer != s {
+			newService.commandServer = s
+			newService.logManager.setCommandServer(s)
+		}
+		if historyStorage, loaded := newService.current().urlTestHistoryStorage.(*urltest.HistoryStorage); loaded {
+			historyStorage.SetHook(s.urlTestUpdate)
+		}
+	}
+	s.access.Lock()
+	s.service.Store(newService)
+	close(s.serviceUpdate)
+	s.serviceUpdate = make(chan struct{})
+	s.access.Unlock()
+	s.notifyURLTestUpdate()
+}
+
+// waitService returns the current service, waiting for SetService if none
+// is set yet.
+func (s *CommandServer) waitService(ctx context.Context) (*BoxService, error) {
+	for {
+		s.access.Lock()
+		service := s.service.Load()
+		serviceUpdate := s.serviceUpdate
+		s.access.Unlock()
+		if service != nil {
+			return service, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-serviceUpdate:
+		}
+	}
+}
+
+// commandInterval converts an interval sent by a client, falling back to one
+// second if it is not positive.
+func commandInterval(interval int64) time.Duration {
+	if interval <= 0 {
+		return time.Second
+	}
+	return time.Duration(interval)
+}
+
+func (s *CommandServer) notifyURLTestUpdate() {
+	select {
+	case s.urlTestUpdate <- struct{}{}:
+	default:
+	}
+}
+
+func (s *CommandServer) notifyModeUpdate() {
+	select {
+	case s.modeUpdate <- struct{}{}:
+	default:
+	}
+}
+
+func (s *CommandServer) Start() error {
+	if !sTVOS {
+		return s.listenUNIX()
+	} else {
+		return s.listenTCP()
+	}
+}
+
+func commandSocketPath() string {
+	return filepath.Join(sBasePath, "command.sock")
+}
+
+func (s *CommandServer) listenUNIX() error {
+	sockPath := commandSocketPath()
+	os.Remove(sockPath)
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{
+		Name: sockPath,
+		Net:  "unix",
+	})
+	if err != nil {
+		return E.Cause(err, "listen ", sockPath)
+	}
+	if runtime.GOOS != "windows" {
+		err = os.Chown(sockPath, sUserID, sGroupID)
+		if err != nil {
+			listener.Close()
+			os.Remove(sockPath)
+			return E.Cause(err, "chown")
+		}
+	}
+	s.listener = listener
+	go s.loopConnection(listener)
+	return nil
+}
+
+func (s *CommandServer) listenTCP() error {
+	listener, err := net.Listen("tcp", "127.0.0.1:8964")
+	if err != nil {
+		return E.Cause(err, "listen")
+	}
+	s.listener = listener
+	go s.loopConnection(listener)
+	return nil
+}
+
+func (s *CommandServer) Close() error {
+	return common.Close(
+		s.listener,
+		s.observer,
+	)
+}
+
+func (s *CommandServer) loopConnection(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			hErr := s.handleConnection(conn)
+			if hErr != nil && !E.IsClosed(hErr) {
+				if debug.Enabled {
+					log.Warn("command-server: process connection: ", hErr)
+				}
+			}
+		}()
+	}
+}
+
+func (s *CommandServer) handleConnection(conn net.Conn) error {
+	defer conn.Close()
+	var command uint8
+	err := binary.Read(conn, binary.BigEndian, &command)
+	if err != nil {
+		return E.Cause(err, "read command")
+	}
+	switch int32(command) {
+	case CommandLog:
+		return s.handleLogConn(conn)
+	case CommandStatus:
+		return s.handleStatusConn(conn)
+	case CommandServiceReload:
+		return s.handleServiceReload(conn)
+	case CommandServiceClose:
+		return s.handleServiceClose(conn)
+	case CommandCloseConnections:
+		return s.handleCloseConnections(conn)
+	case CommandGroup:
+		return s.handleGroupConn(conn)
+	case CommandSelectOutbound:
+		return s.handleSelectOutbound(conn)
+	case CommandClashMode:
+		return s.handleModeConn(conn)
+	case CommandSetClashMode:
+		return s.handleSetClashMode(conn)
+	case CommandConnections:
+		return s.handleConnectionsConn(conn)
+	case CommandCloseConnection:
+		return s.handleCloseConnection(conn)
+	case CommandGetDeprecatedNotes:
+		return s.handleGetDeprecatedNotes(conn)
+	default:
+		return E.New("unknown command: ", command)
+	}
+}
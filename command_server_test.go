@@ -0,0 +1,70 @@
+package liboc
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+type testStatusClientHandler struct {
+	CommandClientHandler
+	status chan *StatusMessage
+	groups chan OutboundGroupIterator
+}
+
+func (h *testStatusClientHandler) Connected() {
+}
+
+func (h *testStatusClientHandler) Disconnected(message string) {
+}
+
+func (h *testStatusClientHandler) WriteStatus(message *StatusMessage) {
+	h.status <- message
+}
+
+func (h *testStatusClientHandler) WriteGroups(message OutboundGroupIterator) {
+	h.groups <- message
+}
+
+func startTestCommandServer(t *testing.T) *CommandServer {
+	sBasePath = t.TempDir()
+	sUserID = os.Getuid()
+	sGroupID = os.Getgid()
+	server := NewCommandServer(nil, 100)
+	err := server.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		server.Close()
+	})
+	return server
+}
+
+func TestCommandZeroInterval(t *testing.T) {
+	startTestCommandServer(t)
+	handler := &testStatusClientHandler{
+		status: make(chan *StatusMessage, 4),
+		groups: make(chan OutboundGroupIterator, 4),
+	}
+	for _, command := range []int32{CommandStatus, CommandGroup} {
+		client := NewCommandClient(handler, &CommandClientOptions{Command: command})
+		err := client.Connect()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer client.Disconnect()
+	}
+	timeout := time.After(5 * time.Second)
+	for range 2 {
+		select {
+		case <-handler.status:
+		case groups := <-handler.groups:
+			if groups.HasNext() {
+				t.Fatal("expected no groups without a service")
+			}
+		case <-timeout:
+			t.Fatal("no message received")
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package liboc
+
+import (
+	"encoding/binary"
+	"net"
+)
+
+func (c *CommandClient) ServiceReload() error {
+	conn, err := c.directConnect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = binary.Write(conn, binary.BigEndian, uint8(CommandServiceReload))
+	if err != nil {
+		return err
+	}
+	return readError(conn)
+}
+
+func (s *CommandServer) handleServiceReload(conn net.Conn) error {
+	return writeError(conn, s.handler.ServiceReload())
+}
+
+func (c *CommandClient) ServiceClose() error {
+	conn, err := c.directConnect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = binary.Write(conn, binary.BigEndian, uint8(CommandServiceClose))
+	if err != nil {
+		return err
+	}
+	return readError(conn)
+}
+
+func (s *CommandServer) handleServiceClose(conn net.Conn) error {
+	var rErr error
+	if service := s.service.Load(); service != nil {
+		rErr = service.Close()
+		s.SetService(nil)
+	}
+	s.handler.PostServiceClose()
+	return writeError(conn, rErr)
+}
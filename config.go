@@ -0,0 +1,382 @@
+package liboc
+
+import (
+	"bytes"
+	"context"
+	stdjson "encoding/json"
+	"errors"
+	"io"
+	"net/netip"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sagernet/sing-box"
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/process"
+	"github.com/sagernet/sing-box/experimental/libbox/platform"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-tun"
+	"github.com/sagernet/sing/common/control"
+	"github.com/sagernet/sing/common/json"
+	"github.com/sagernet/sing/common/logger"
+	"github.com/sagernet/sing/common/x/list"
+	"github.com/sagernet/sing/service"
+)
+
+// ConfigError describes why a configuration was rejected. Path is the
+// location of the failing field such as "outbounds[0].tag", and Line and
+// Column are 1-based positions in the content, or zero when unknown.
+type ConfigError struct {
+	Message string
+	Path    string
+	Line    int32
+	Column  int32
+}
+
+func (e *ConfigError) Error() string {
+	message := e.Message
+	if e.Path != "" {
+		message = e.Path + ": " + message
+	}
+	if e.Line > 0 {
+		message += " (line " + strconv.Itoa(int(e.Line)) + ", column " + strconv.Itoa(int(e.Column)) + ")"
+	}
+	return message
+}
+
+// CheckConfig parses configContent and creates, without starting, a service
+// from it. It returns nil if the configuration is valid, or an error wrapping
+// a *ConfigError otherwise.
+func CheckConfig(configContent string) error {
+	ctx := BaseContext(nil)
+	options, configErr := decodeConfig(ctx, configContent)
+	if configErr != nil {
+		return configErr
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ctx = service.ContextWith[platform.Interface](ctx, (*platformInterfaceStub)(nil))
+	instance, err := box.New(box.Options{
+		Context: ctx,
+		Options: options,
+	})
+	if err != nil {
+		return newConfigCreateError(configContent, err)
+	}
+	instance.Close()
+	return nil
+}
+
+// FormatConfig returns configContent re-encoded with two-space indentation.
+// A parse failure is returned as a *ConfigError.
+func FormatConfig(configContent string) (*StringBox, error) {
+	options, configErr := decodeConfig(BaseContext(nil), configContent)
+	if configErr != nil {
+		return nil, configErr
+	}
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetIndent("", "  ")
+	err := encoder.Encode(options)
+	if err != nil {
+		return nil, err
+	}
+	return wrapString(buffer.String()), nil
+}
+
+func decodeConfig(ctx context.Context, configContent string) (option.Options, *ConfigError) {
+	options, err := json.UnmarshalExtendedContext[option.Options](ctx, []byte(configContent))
+	if err != nil {
+		return option.Options{}, newConfigParseError(configContent, err)
+	}
+	return options, nil
+}
+
+// decodeContextError matches the unexported error the context-aware decoder
+// wraps failures in to prefix them with the path of the field being decoded.
+// Nested decoders join their paths with a dot and the innermost path is
+// joined to the cause with a colon.
+type decodeContextError interface {
+	error
+	Unwrap() error
+}
+
+func newConfigParseError(configContent string, err error) *ConfigError {
+	content := filterConfigComments(configContent)
+	var syntaxError *json.SyntaxError
+	if errors.As(err, &syntaxError) {
+		line, column := configPosition(content, syntaxError.Offset)
+		return &ConfigError{
+			Message: syntaxError.Error(),
+			Line:    line,
+			Column:  column,
+		}
+	}
+	cause := err
+	for {
+		var contextErr decodeContextError
+		if !errors.As(cause, &contextErr) {
+			break
+		}
+		parent := contextErr.Unwrap()
+		if parent == nil {
+			break
+		}
+		cause = parent
+		if !strings.HasSuffix(contextErr.Error(), "."+parent.Error()) {
+			break
+		}
+	}
+	configErr := &ConfigError{Message: cause.Error()}
+	if cause != err {
+		configErr.Path = strings.TrimSuffix(err.Error(), ": "+cause.Error())
+		configErr.Line, configErr.Column = locateConfigPath(content, configErr.Path)
+	}
+	return configErr
+}
+
+var configCreatePaths = []struct {
+	pattern *regexp.Regexp
+	path    string
+}{
+	{regexp.MustCompile(`initialize inbound\[(\d+)]`), "inbounds[$1]"},
+	{regexp.MustCompile(`initialize outbound\[(\d+)]`), "outbounds[$1]"},
+	{regexp.MustCompile(`initialize endpoint\[(\d+)]`), "endpoints[$1]"},
+	{regexp.MustCompile(`initialize service\[(\d+)]`), "services[$1]"},
+	{regexp.MustCompile(`initialize DNS server\[(\d+)]`), "dns.servers[$1]"},
+	{regexp.MustCompile(`parse dns rule\[(\d+)]`), "dns.rules[$1]"},
+	{regexp.MustCompile(`parse rule-set\[(\d+)]`), "route.rule_set[$1]"},
+	{regexp.MustCompile(`parse rule\[(\d+)]`), "route.rules[$1]"},
+	{regexp.MustCompile(`create log factory`), "log"},
+	{regexp.MustCompile(`create clash-server`), "experimental.clash_api"},
+	{regexp.MustCompile(`create v2ray-server`), "experimental.v2ray_api"},
+	{regexp.MustCompile(`create NTP service`), "ntp"},
+}
+
+// newConfigCreateError maps the component prefix of a service creation error
+// to the configuration entry it was created from.
+func newConfigCreateError(configContent string, err error) *ConfigError {
+	message := err.Error()
+	for _, createPath := range configCreatePaths {
+		match := createPath.pattern.FindStringSubmatchIndex(message)
+		if match == nil {
+			continue
+		}
+		path := string(createPath.pattern.ExpandString(nil, createPath.path, message, match))
+		line, column := locateConfigPath(filterConfigComments(configContent), path)
+		return &ConfigError{
+			Message: message,
+			Path:    path,
+			Line:    line,
+			Column:  column,
+		}
+	}
+	return &ConfigError{Message: message}
+}
+
+// filterConfigComments strips comments the same way the decoder does, so
+// offsets reported by it can be mapped back to lines and columns. Positions
+// after a multi-line comment are therefore approximate.
+func filterConfigComments(configContent string) []byte {
+	content, err := io.ReadAll(json.NewCommentFilter(strings.NewReader(configContent)))
+	if err != nil {
+		return []byte(configContent)
+	}
+	return content
+}
+
+func configPosition(content []byte, offset int64) (line int32, column int32) {
+	if offset < 0 || offset > int64(len(content)) {
+		return 0, 0
+	}
+	prefix := content[:offset]
+	line = int32(bytes.Count(prefix, []byte("\n")) + 1)
+	column = int32(len(prefix)-bytes.LastIndexByte(prefix, '\n')-1) + 1
+	return
+}
+
+type configPathSegment struct {
+	key   string
+	index int
+}
+
+func parseConfigPath(path string) []configPathSegment {
+	var segments []configPathSegment
+	for _, part := range strings.Split(path, ".") {
+		key, indexes, _ := strings.Cut(part, "[")
+		if key != "" {
+			segments = append(segments, configPathSegment{key: key, index: -1})
+		}
+		if indexes == "" {
+			continue
+		}
+		for _, index := range strings.Split(strings.TrimSuffix(indexes, "]"), "][") {
+			value, err := strconv.Atoi(index)
+			if err != nil {
+				return segments
+			}
+			segments = append(segments, configPathSegment{index: value})
+		}
+	}
+	return segments
+}
+
+// locateConfigPath returns the position of the value at path, or of its
+// deepest existing parent.
+func locateConfigPath(content []byte, path string) (line int32, column int32) {
+	if path == "" {
+		return 0, 0
+	}
+	return locateConfigSegments(content, parseConfigPath(path))
+}
+
+func locateConfigSegments(content []byte, segments []configPathSegment) (line int32, column int32) {
+	decoder := stdjson.NewDecoder(bytes.NewReader(content))
+	offset := seekConfigPath(decoder, segments, 0)
+	for offset < int64(len(content)) && strings.IndexByte(" \t\r\n:,", content[offset]) >= 0 {
+		offset++
+	}
+	return configPosition(content, offset)
+}
+
+func seekConfigPath(decoder *stdjson.Decoder, segments []configPathSegment, offset int64) int64 {
+	if len(segments) == 0 {
+		return offset
+	}
+	token, err := decoder.Token()
+	if err != nil {
+		return offset
+	}
+	segment := segments[0]
+	switch token {
+	case stdjson.Delim('{'):
+		if segment.index >= 0 {
+			return offset
+		}
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return offset
+			}
+			if key == segment.key {
+				return seekConfigPath(decoder, segments[1:], decoder.InputOffset())
+			}
+			if skipConfigValue(decoder) != nil {
+				return offset
+			}
+		}
+	case stdjson.Delim('['):
+		if segment.index < 0 {
+			return offset
+		}
+		for index := 0; decoder.More(); index++ {
+			if index == segment.index {
+				return seekConfigPath(decoder, segments[1:], decoder.InputOffset())
+			}
+			if skipConfigValue(decoder) != nil {
+				return offset
+			}
+		}
+	}
+	return offset
+}
+
+func skipConfigValue(decoder *stdjson.Decoder) error {
+	var value stdjson.RawMessage
+	return decoder.Decode(&value)
+}
+
+type platformInterfaceStub struct{}
+
+func (s *platformInterfaceStub) Initialize(networkManager adapter.NetworkManager) error {
+	return nil
+}
+
+func (s *platformInterfaceStub) UsePlatformAutoDetectInterfaceControl() bool {
+	return true
+}
+
+func (s *platformInterfaceStub) AutoDetectInterfaceControl(fd int) error {
+	return nil
+}
+
+func (s *platformInterfaceStub) OpenTun(options *tun.Options, platformOptions option.TunPlatformOptions) (tun.Tun, error) {
+	return nil, os.ErrInvalid
+}
+
+func (s *platformInterfaceStub) UsePlatformDefaultInterfaceMonitor() bool {
+	return true
+}
+
+func (s *platformInterfaceStub) CreateDefaultInterfaceMonitor(logger logger.Logger) tun.DefaultInterfaceMonitor {
+	return (*interfaceMonitorStub)(nil)
+}
+
+func (s *platformInterfaceStub) Interfaces() ([]adapter.NetworkInterface, error) {
+	return nil, os.ErrInvalid
+}
+
+func (s *platformInterfaceStub) UnderNetworkExtension() bool {
+	return false
+}
+
+func (s *platformInterfaceStub) IncludeAllNetworks() bool {
+	return false
+}
+
+func (s *platformInterfaceStub) ClearDNSCache() {
+}
+
+func (s *platformInterfaceStub) ReadWIFIState() adapter.WIFIState {
+	return adapter.WIFIState{}
+}
+
+func (s *platformInterfaceStub) SystemCertificates() []string {
+	return nil
+}
+
+func (s *platformInterfaceStub) FindProcessInfo(ctx context.Context, network string, source netip.AddrPort, destination netip.AddrPort) (*process.Info, error) {
+	return nil, os.ErrInvalid
+}
+
+func (s *platformInterfaceStub) SendNotification(notification *platform.Notification) error {
+	return nil
+}
+
+type interfaceMonitorStub struct{}
+
+func (s *interfaceMonitorStub) Start() error {
+	return os.ErrInvalid
+}
+
+func (s *interfaceMonitorStub) Close() error {
+	return os.ErrInvalid
+}
+
+func (s *interfaceMonitorStub) DefaultInterface() *control.Interface {
+	return nil
+}
+
+func (s *interfaceMonitorStub) OverrideAndroidVPN() bool {
+	return false
+}
+
+func (s *interfaceMonitorStub) AndroidVPNEnabled() bool {
+	return false
+}
+
+func (s *interfaceMonitorStub) RegisterCallback(callback tun.DefaultInterfaceUpdateCallback) *list.Element[tun.DefaultInterfaceUpdateCallback] {
+	return nil
+}
+
+func (s *interfaceMonitorStub) UnregisterCallback(element *list.Element[tun.DefaultInterfaceUpdateCallback]) {
+}
+
+func (s *interfaceMonitorStub) RegisterMyInterface(interfaceName string) {
+}
+
+func (s *interfaceMonitorStub) MyInterface() string {
+	return ""
+}
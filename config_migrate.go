@@ -0,0 +1,428 @@
+package liboc
+
+import (
+	"bytes"
+	stdjson "encoding/json"
+	"net/url"
+	"strconv"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/experimental/deprecated"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json/badjson"
+	M "github.com/sagernet/sing/common/metadata"
+)
+
+type ConfigMigration struct {
+	// Content is the migrated configuration, or the original content if
+	// nothing was migrated.
+	Content string
+	notes   []DeprecatedNote
+}
+
+// Notes returns the deprecated features that were rewritten.
+func (m *ConfigMigration) Notes() DeprecatedNoteIterator {
+	return newPtrIterator(m.notes)
+}
+
+// MigrateConfig rewrites deprecated constructs in configContent to their
+// current equivalents: legacy tun address fields, the tun GSO option, legacy
+// inbound sniff and domain strategy fields, block and dns outbounds, and
+// legacy DNS servers together with the DNS fakeip options. Constructs that
+// cannot be expressed in the new format are left unchanged. Comments are not
+// preserved when anything is rewritten.
+func MigrateConfig(configContent string) (*ConfigMigration, error) {
+	content := filterConfigComments(configContent)
+	rawOptions, err := badjson.Decode(BaseContext(nil), content)
+	if err != nil {
+		return nil, E.Cause(err, "decode config")
+	}
+	options, isObject := rawOptions.(*badjson.JSONObject)
+	if !isObject {
+		return nil, E.New("decode config: configuration is not an object")
+	}
+	migrator := &configMigrator{options: options}
+	migrator.migrateTunFields()
+	migrator.migrateInboundFields()
+	migrator.migrateSpecialOutbounds()
+	migrator.migrateDNSServers()
+	if len(migrator.notes) == 0 {
+		return &ConfigMigration{Content: configContent}, nil
+	}
+	migratedContent, err := options.MarshalJSON()
+	if err != nil {
+		return nil, E.Cause(err, "encode config")
+	}
+	var buffer bytes.Buffer
+	err = stdjson.Indent(&buffer, migratedContent, "", "  ")
+	if err != nil {
+		return nil, E.Cause(err, "encode config")
+	}
+	buffer.WriteByte('\n')
+	_, configErr := decodeConfig(BaseContext(nil), buffer.String())
+	if configErr != nil {
+		return nil, E.Cause(configErr, "check migrated config")
+	}
+	return &ConfigMigration{
+		Content: buffer.String(),
+		notes:   migrator.notes,
+	}, nil
+}
+
+type configMigrator struct {
+	options *badjson.JSONObject
+	notes   []DeprecatedNote
+}
+
+func (m *configMigrator) report(note deprecated.Note) {
+	for _, reported := range m.notes {
+		if reported.Name == note.Name {
+			return
+		}
+	}
+	m.notes = append(m.notes, DeprecatedNote(note))
+}
+
+func jsonObject(object *badjson.JSONObject, key string) *badjson.JSONObject {
+	value, _ := object.Get(key)
+	child, _ := value.(*badjson.JSONObject)
+	return child
+}
+
+func jsonArray(object *badjson.JSONObject, key string) badjson.JSONArray {
+	value, _ := object.Get(key)
+	array, _ := value.(badjson.JSONArray)
+	return array
+}
+
+func jsonString(object *badjson.JSONObject, key string) string {
+	value, _ := object.Get(key)
+	stringValue, _ := value.(string)
+	return stringValue
+}
+
+// jsonList flattens a value that may be a single item or a list of items.
+func jsonList(value any) badjson.JSONArray {
+	switch value := value.(type) {
+	case nil:
+		return nil
+	case badjson.JSONArray:
+		return value
+	default:
+		return badjson.JSONArray{value}
+	}
+}
+
+func (m *configMigrator) objectsOf(key string) []*badjson.JSONObject {
+	var objects []*badjson.JSONObject
+	for _, value := range jsonArray(m.options, key) {
+		object, isObject := value.(*badjson.JSONObject)
+		if isObject {
+			objects = append(objects, object)
+		}
+	}
+	return objects
+}
+
+// migrateTunFields merges inet4_* and inet6_* tun fields into address,
+// route_address and route_exclude_address, and drops gso.
+func (m *configMigrator) migrateTunFields() {
+	for _, inbound := range m.objectsOf("inbounds") {
+		if jsonString(inbound, "type") != C.TypeTun {
+			continue
+		}
+		for _, fields := range [][3]string{
+			{"address", "inet4_address", "inet6_address"},
+			{"route_address", "inet4_route_address", "inet6_route_address"},
+			{"route_exclude_address", "inet4_route_exclude_address", "inet6_route_exclude_address"},
+		} {
+			var merged badjson.JSONArray
+			for _, field := range fields[1:] {
+				value, loaded := inbound.Get(field)
+				if !loaded {
+					continue
+				}
+				merged = append(merged, jsonList(value)...)
+				inbound.Remove(field)
+			}
+			if merged == nil {
+				continue
+			}
+			existing, _ := inbound.Get(fields[0])
+			inbound.Put(fields[0], append(jsonList(existing), merged...))
+			m.report(deprecated.OptionTUNAddressX)
+		}
+		if inbound.ContainsKey("gso") {
+			inbound.Remove("gso")
+			m.report(deprecated.OptionTUNGSO)
+		}
+	}
+}
+
+// migrateInboundFields replaces the sniff and domain strategy inbound fields
+// with sniff and resolve rules placed before the existing route rules.
+// Inbounds using sniff_override_destination are left unchanged, since the
+// sniff action has no equivalent of it.
+func (m *configMigrator) migrateInboundFields() {
+	var newRules badjson.JSONArray
+	inbounds := m.objectsOf("inbounds")
+	tags := make(map[string]bool)
+	for _, inbound := range inbounds {
+		tags[jsonString(inbound, "tag")] = true
+	}
+	for _, inbound := range inbounds {
+		sniff, _ := inbound.Get("sniff")
+		sniffTimeout, hasSniffTimeout := inbound.Get("sniff_timeout")
+		domainStrategy := jsonString(inbound, "domain_strategy")
+		if sniff != true && domainStrategy == "" {
+			continue
+		}
+		if sniffOverrideDestination, _ := inbound.Get("sniff_override_destination"); sniffOverrideDestination == true {
+			continue
+		}
+		tag := jsonString(inbound, "tag")
+		if tag == "" {
+			tag = jsonString(inbound, "type") + "-in"
+			for i := 1; tags[tag]; i++ {
+				tag = jsonString(inbound, "type") + "-in-" + strconv.Itoa(i)
+			}
+			tags[tag] = true
+			inbound.Put("tag", tag)
+		}
+		if sniff == true {
+			rule := new(badjson.JSONObject)
+			rule.Put("inbound", tag)
+			rule.Put("action", C.RuleActionTypeSniff)
+			if hasSniffTimeout {
+				rule.Put("timeout", sniffTimeout)
+			}
+			newRules = append(newRules, rule)
+			inbound.Remove("sniff")
+			inbound.Remove("sniff_timeout")
+		}
+		if domainStrategy != "" {
+			rule := new(badjson.JSONObject)
+			rule.Put("inbound", tag)
+			rule.Put("action", C.RuleActionTypeResolve)
+			rule.Put("strategy", domainStrategy)
+			newRules = append(newRules, rule)
+			inbound.Remove("domain_strategy")
+		}
+		m.report(deprecated.OptionInboundOptions)
+	}
+	if len(newRules) == 0 {
+		return
+	}
+	route := m.ensureObject(m.options, "route")
+	route.Put("rules", append(newRules, jsonArray(route, "rules")...))
+}
+
+func (m *configMigrator) ensureObject(object *badjson.JSONObject, key string) *badjson.JSONObject {
+	child := jsonObject(object, key)
+	if child == nil {
+		child = new(badjson.JSONObject)
+		object.Put(key, child)
+	}
+	return child
+}
+
+// migrateSpecialOutbounds turns rules routing to block and dns outbounds
+// into reject and hijack-dns actions, and removes those outbounds once
+// nothing else refers to them.
+func (m *configMigrator) migrateSpecialOutbounds() {
+	actions := make(map[string]string)
+	for _, outbound := range m.objectsOf("outbounds") {
+		var action string
+		switch jsonString(outbound, "type") {
+		case C.TypeBlock:
+			action = C.RuleActionTypeReject
+		case C.TypeDNS:
+			action = C.RuleActionTypeHijackDNS
+		default:
+			continue
+		}
+		tag := jsonString(outbound, "tag")
+		if tag != "" {
+			actions[tag] = action
+		}
+	}
+	if len(actions) == 0 {
+		return
+	}
+	route := jsonObject(m.options, "route")
+	if route != nil {
+		for _, rule := range jsonArray(route, "rules") {
+			m.migrateSpecialOutboundRule(rule, actions)
+		}
+	}
+	referenced := make(map[string]bool)
+	if route != nil {
+		referenced[jsonString(route, "final")] = true
+	}
+	for _, outbound := range append(m.objectsOf("outbounds"), m.objectsOf("endpoints")...) {
+		groupOutbounds, _ := outbound.Get("outbounds")
+		for _, tag := range jsonList(groupOutbounds) {
+			tagString, _ := tag.(string)
+			referenced[tagString] = true
+		}
+		referenced[jsonString(outbound, "default")] = true
+		referenced[jsonString(outbound, "detour")] = true
+	}
+	var outbounds badjson.JSONArray
+	for _, value := range jsonArray(m.options, "outbounds") {
+		outbound, isObject := value.(*badjson.JSONObject)
+		if isObject {
+			tag := jsonString(outbound, "tag")
+			if _, isSpecial := actions[tag]; isSpecial && !referenced[tag] {
+				m.report(deprecated.OptionSpecialOutbounds)
+				continue
+			}
+		}
+		outbounds = append(outbounds, value)
+	}
+	m.options.Put("outbounds", outbounds)
+}
+
+func (m *configMigrator) migrateSpecialOutboundRule(value any, actions map[string]string) {
+	rule, isObject := value.(*badjson.JSONObject)
+	if !isObject {
+		return
+	}
+	for _, subRule := range jsonArray(rule, "rules") {
+		m.migrateSpecialOutboundRule(subRule, actions)
+	}
+	action := jsonString(rule, "action")
+	if action != "" && action != C.RuleActionTypeRoute {
+		return
+	}
+	newAction, isSpecial := actions[jsonString(rule, "outbound")]
+	if !isSpecial {
+		return
+	}
+	rule.Remove("outbound")
+	rule.Put("action", newAction)
+	m.report(deprecated.OptionSpecialOutbounds)
+}
+
+// migrateDNSServers converts legacy address based DNS servers to typed
+// servers. Servers using fields without a typed equivalent are kept.
+func (m *configMigrator) migrateDNSServers() {
+	dnsOptions := jsonObject(m.options, "dns")
+	if dnsOptions == nil {
+		return
+	}
+	fakeIPOptions := jsonObject(dnsOptions, "fakeip")
+	var (
+		usedFakeIP bool
+		servers    badjson.JSONArray
+	)
+	for _, value := range jsonArray(dnsOptions, "servers") {
+		server, isObject := value.(*badjson.JSONObject)
+		if isObject && server.ContainsKey("address") && !server.ContainsKey("type") {
+			newServer := migrateDNSServer(server, fakeIPOptions)
+			if newServer != nil {
+				if jsonString(newServer, "type") == C.DNSTypeFakeIP {
+					usedFakeIP = true
+				}
+				value = newServer
+				m.report(deprecated.OptionLegacyDNSTransport)
+			}
+		}
+		servers = append(servers, value)
+	}
+	if servers != nil {
+		dnsOptions.Put("servers", servers)
+	}
+	if fakeIPOptions != nil && usedFakeIP {
+		dnsOptions.Remove("fakeip")
+		m.report(deprecated.OptionLegacyDNSFakeIPOptions)
+	}
+}
+
+func migrateDNSServer(server *badjson.JSONObject, fakeIPOptions *badjson.JSONObject) *badjson.JSONObject {
+	for _, key := range server.Keys() {
+		switch key {
+		case "tag", "address", "address_resolver", "address_strategy", "detour":
+		default:
+			return nil
+		}
+	}
+	address := jsonString(server, "address")
+	serverURL, _ := url.Parse(address)
+	var serverType string
+	if serverURL != nil && serverURL.Scheme != "" {
+		serverType = serverURL.Scheme
+	} else {
+		switch address {
+		case C.DNSTypeLocal, C.DNSTypeFakeIP:
+			serverType = address
+		default:
+			serverType = C.DNSTypeUDP
+		}
+	}
+	newServer := new(badjson.JSONObject)
+	newServer.Put("type", serverType)
+	if tag := jsonString(server, "tag"); tag != "" {
+		newServer.Put("tag", tag)
+	}
+	switch serverType {
+	case C.DNSTypeLocal:
+	case C.DNSTypeUDP, C.DNSTypeTCP, C.DNSTypeTLS, C.DNSTypeQUIC, C.DNSTypeHTTPS, C.DNSTypeHTTP3:
+		host := address
+		if serverURL != nil && serverURL.Scheme != "" {
+			host = serverURL.Host
+		}
+		serverAddr := M.ParseSocksaddr(host)
+		if !serverAddr.IsValid() {
+			return nil
+		}
+		newServer.Put("server", serverAddr.AddrString())
+		var defaultPort uint16
+		switch serverType {
+		case C.DNSTypeUDP, C.DNSTypeTCP:
+			defaultPort = 53
+		case C.DNSTypeTLS, C.DNSTypeQUIC:
+			defaultPort = 853
+		default:
+			defaultPort = 443
+		}
+		if serverAddr.Port != 0 && serverAddr.Port != defaultPort {
+			newServer.Put("server_port", serverAddr.Port)
+		}
+		if (serverType == C.DNSTypeHTTPS || serverType == C.DNSTypeHTTP3) && serverURL.Path != "/dns-query" {
+			newServer.Put("path", serverURL.Path)
+		}
+	case C.DNSTypeDHCP:
+		if serverURL.Host != "" && serverURL.Host != "auto" {
+			newServer.Put("interface", serverURL.Host)
+		}
+	case C.DNSTypeFakeIP:
+		if fakeIPOptions != nil {
+			for _, key := range []string{"inet4_range", "inet6_range"} {
+				value, loaded := fakeIPOptions.Get(key)
+				if loaded {
+					newServer.Put(key, value)
+				}
+			}
+		}
+	default:
+		return nil
+	}
+	if serverType == C.DNSTypeFakeIP {
+		return newServer
+	}
+	if detour := jsonString(server, "detour"); detour != "" {
+		newServer.Put("detour", detour)
+	}
+	if resolver := jsonString(server, "address_resolver"); resolver != "" {
+		if strategy := jsonString(server, "address_strategy"); strategy != "" {
+			domainResolver := new(badjson.JSONObject)
+			domainResolver.Put("server", resolver)
+			domainResolver.Put("strategy", strategy)
+			newServer.Put("domain_resolver", domainResolver)
+		} else {
+			newServer.Put("domain_resolver", resolver)
+		}
+	}
+	return newServer
+}
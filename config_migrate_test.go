@@ -0,0 +1,109 @@
+package liboc
+
+import (
+	stdjson "encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMigrateConfig(t *testing.T) {
+	for _, testCase := range []struct {
+		name     string
+		config   string
+		migrated string
+		notes    int
+	}{
+		{
+			name: "sniff",
+			config: `{
+  "inbounds": [{"type": "mixed", "tag": "mixed-in", "listen_port": 1080, "sniff": true, "sniff_timeout": "1s"}]
+}`,
+			migrated: `{
+  "inbounds": [{"type": "mixed", "tag": "mixed-in", "listen_port": 1080}],
+  "route": {"rules": [{"inbound": "mixed-in", "action": "sniff", "timeout": "1s"}]}
+}`,
+			notes: 1,
+		},
+		{
+			name: "sniff with override destination",
+			config: `{
+  "inbounds": [{"type": "mixed", "tag": "mixed-in", "listen_port": 1080, "sniff": true, "sniff_override_destination": true, "domain_strategy": "ipv4_only"}]
+}`,
+		},
+		{
+			name: "domain strategy",
+			config: `{
+  "inbounds": [{"type": "mixed", "listen_port": 1080, "domain_strategy": "ipv4_only"}],
+  "route": {"rules": [{"domain": "example.com", "outbound": "direct"}]},
+  "outbounds": [{"type": "direct", "tag": "direct"}]
+}`,
+			migrated: `{
+  "inbounds": [{"type": "mixed", "listen_port": 1080, "tag": "mixed-in"}],
+  "route": {"rules": [
+    {"inbound": "mixed-in", "action": "resolve", "strategy": "ipv4_only"},
+    {"domain": "example.com", "outbound": "direct"}
+  ]},
+  "outbounds": [{"type": "direct", "tag": "direct"}]
+}`,
+			notes: 1,
+		},
+		{
+			name: "block and dns outbounds",
+			config: `{
+  "outbounds": [
+    {"type": "direct", "tag": "direct"},
+    {"type": "block", "tag": "block"},
+    {"type": "dns", "tag": "dns-out"}
+  ],
+  "route": {"rules": [
+    {"protocol": "dns", "outbound": "dns-out"},
+    {"domain": "ads.example.com", "outbound": "block"}
+  ]}
+}`,
+			migrated: `{
+  "outbounds": [{"type": "direct", "tag": "direct"}],
+  "route": {"rules": [
+    {"protocol": "dns", "action": "hijack-dns"},
+    {"domain": "ads.example.com", "action": "reject"}
+  ]}
+}`,
+			notes: 1,
+		},
+		{
+			name: "referenced block outbound",
+			config: `{
+  "outbounds": [{"type": "block", "tag": "block"}],
+  "route": {"final": "block"}
+}`,
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			migration, err := MigrateConfig(testCase.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			notes := iteratorToArray[*DeprecatedNote](migration.Notes())
+			if len(notes) != testCase.notes {
+				t.Fatalf("expected %d notes, got %d", testCase.notes, len(notes))
+			}
+			if testCase.migrated == "" {
+				if migration.Content != testCase.config {
+					t.Fatalf("expected config unchanged, got %s", migration.Content)
+				}
+				return
+			}
+			var migrated, expected any
+			err = stdjson.Unmarshal([]byte(migration.Content), &migrated)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = stdjson.Unmarshal([]byte(testCase.migrated), &expected)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(migrated, expected) {
+				t.Fatalf("unexpected migrated config:\n%s", migration.Content)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package liboc
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/json"
+)
+
+const testConfigWrongType = `{
+  "outbounds": [
+    {
+      "type": "direct",
+      "tag": 1
+    }
+  ]
+}`
+
+// TestConfigDecodeContextError fails if the decoder stops wrapping errors
+// in the path-prefixing error newConfigParseError unwraps.
+func TestConfigDecodeContextError(t *testing.T) {
+	_, err := json.UnmarshalExtendedContext[option.Options](BaseContext(nil), []byte(testConfigWrongType))
+	if err == nil {
+		t.Fatal("expected decode error")
+	}
+	var contextErr decodeContextError
+	if !errors.As(err, &contextErr) || contextErr.Unwrap() == nil {
+		t.Fatalf("decoder error %T is not a context error", err)
+	}
+	configErr := newConfigParseError(testConfigWrongType, err)
+	if configErr.Path != "outbounds[0].tag" || strings.HasPrefix(configErr.Message, configErr.Path) {
+		t.Fatalf("unexpected error path: %+v", configErr)
+	}
+}
+
+func TestConfigParseErrorWithoutContext(t *testing.T) {
+	configErr := newConfigParseError(testConfigWrongType, errors.New("duplicate outbound/endpoint tag: direct"))
+	if configErr.Path != "" || configErr.Message != "duplicate outbound/endpoint tag: direct" {
+		t.Fatalf("unexpected error: %+v", configErr)
+	}
+}
+
+func TestCheckConfigParseError(t *testing.T) {
+	var configErr *ConfigError
+	if !errors.As(CheckConfig(testConfigWrongType), &configErr) {
+		t.Fatal("expected *ConfigError")
+	}
+	if configErr.Path != "outbounds[0].tag" || configErr.Line != 5 || configErr.Column != 14 {
+		t.Fatalf("unexpected error location: %+v", configErr)
+	}
+}
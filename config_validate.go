@@ -0,0 +1,322 @@
+package liboc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sagernet/sing-box"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/experimental/libbox/platform"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/json"
+	"github.com/sagernet/sing/service"
+)
+
+const (
+	ConfigDiagnosticError int32 = iota
+	ConfigDiagnosticWarning
+)
+
+// ConfigDiagnostic is a problem found in a configuration. Pointer is an
+// RFC 6901 JSON pointer such as "/outbounds/0/tag", and Line and Column are
+// 1-based positions in the content, or zero when unknown.
+type ConfigDiagnostic struct {
+	Severity int32
+	Pointer  string
+	Line     int32
+	Column   int32
+	Message  string
+}
+
+type ConfigDiagnosticIterator interface {
+	Next() *ConfigDiagnostic
+	HasNext() bool
+}
+
+var _ ConfigDiagnosticIterator = (*iterator[*ConfigDiagnostic])(nil)
+
+// ValidateConfig returns every problem found in configContent, with messages
+// from the current Locale. A syntax or type error stops validation at the
+// first problem; otherwise references between tags are checked, and the
+// configuration is created, without starting, if no error was found.
+func ValidateConfig(configContent string) ConfigDiagnosticIterator {
+	validator := &configValidator{
+		content:      filterConfigComments(configContent),
+		outbounds:    make(map[string]bool),
+		dnsServers:   make(map[string]bool),
+		ruleSets:     make(map[string]bool),
+		usedRuleSets: make(map[string]bool),
+	}
+	ctx := BaseContext(nil)
+	options, configErr := decodeConfigUnchecked(ctx, configContent)
+	if configErr != nil {
+		validator.reportConfigError(configErr)
+		return newPtrIterator(validator.diagnostics)
+	}
+	validator.validate(options)
+	if !validator.hasError() {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		ctx = service.ContextWith[platform.Interface](ctx, (*platformInterfaceStub)(nil))
+		instance, err := box.New(box.Options{
+			Context: ctx,
+			Options: options,
+		})
+		if err != nil {
+			validator.reportConfigError(newConfigCreateError(configContent, err))
+		} else {
+			instance.Close()
+		}
+	}
+	return newPtrIterator(validator.diagnostics)
+}
+
+// uncheckedOptions skips the checks option.Options runs after decoding, so
+// that duplicate tags are reported along with other problems.
+type uncheckedOptions option.Options
+
+func decodeConfigUnchecked(ctx context.Context, configContent string) (option.Options, *ConfigError) {
+	content := filterConfigComments(configContent)
+	decoder := json.NewDecoderContext(ctx, bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+	var options uncheckedOptions
+	err := decoder.Decode(&options)
+	if err != nil {
+		return option.Options{}, newConfigParseError(configContent, err)
+	}
+	options.RawMessage = content
+	return option.Options(options), nil
+}
+
+type configValidator struct {
+	content      []byte
+	diagnostics  []ConfigDiagnostic
+	outbounds    map[string]bool
+	dnsServers   map[string]bool
+	ruleSets     map[string]bool
+	usedRuleSets map[string]bool
+}
+
+// configPath builds path segments from keys and indexes.
+func configPath(elements ...any) []configPathSegment {
+	segments := make([]configPathSegment, 0, len(elements))
+	for _, element := range elements {
+		switch element := element.(type) {
+		case string:
+			segments = append(segments, configPathSegment{key: element, index: -1})
+		case int:
+			segments = append(segments, configPathSegment{index: element})
+		case []configPathSegment:
+			segments = append(segments, element...)
+		}
+	}
+	return segments
+}
+
+func configPointer(segments []configPathSegment) string {
+	var builder strings.Builder
+	for _, segment := range segments {
+		builder.WriteString("/")
+		if segment.index >= 0 {
+			builder.WriteString(strconv.Itoa(segment.index))
+		} else {
+			builder.WriteString(strings.ReplaceAll(strings.ReplaceAll(segment.key, "~", "~0"), "/", "~1"))
+		}
+	}
+	return builder.String()
+}
+
+func (v *configValidator) report(severity int32, segments []configPathSegment, format string, args ...any) {
+	line, column := locateConfigSegments(v.content, segments)
+	v.diagnostics = append(v.diagnostics, ConfigDiagnostic{
+		Severity: severity,
+		Pointer:  configPointer(segments),
+		Line:     line,
+		Column:   column,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (v *configValidator) reportConfigError(configErr *ConfigError) {
+	message := configErr.Message
+	if configErr.Path != "" {
+		message = configErr.Path + ": " + message
+	}
+	var segments []configPathSegment
+	if configErr.Path != "" {
+		segments = parseConfigPath(configErr.Path)
+	}
+	v.diagnostics = append(v.diagnostics, ConfigDiagnostic{
+		Severity: ConfigDiagnosticError,
+		Pointer:  configPointer(segments),
+		Line:     configErr.Line,
+		Column:   configErr.Column,
+		Message:  fmt.Sprintf(Current().InvalidConfigMessage, message),
+	})
+}
+
+func (v *configValidator) hasError() bool {
+	for _, diagnostic := range v.diagnostics {
+		if diagnostic.Severity == ConfigDiagnosticError {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *configValidator) validate(options option.Options) {
+	for i, endpoint := range options.Endpoints {
+		v.defineTag(v.outbounds, configPath("endpoints", i, "tag"), endpoint.Tag, i)
+	}
+	for i, outbound := range options.Outbounds {
+		v.defineTag(v.outbounds, configPath("outbounds", i, "tag"), outbound.Tag, i)
+	}
+	inbounds := make(map[string]bool)
+	for i, inbound := range options.Inbounds {
+		v.defineTag(inbounds, configPath("inbounds", i, "tag"), inbound.Tag, i)
+	}
+	if options.DNS != nil {
+		for i, server := range options.DNS.Servers {
+			v.defineTag(v.dnsServers, configPath("dns", "servers", i, "tag"), server.Tag, i)
+		}
+	}
+	if options.Route != nil {
+		for i, ruleSet := range options.Route.RuleSet {
+			v.defineTag(v.ruleSets, configPath("route", "rule_set", i, "tag"), ruleSet.Tag, i)
+		}
+	}
+	for i, endpoint := range options.Endpoints {
+		v.checkDialer(configPath("endpoints", i), endpoint.Options)
+	}
+	for i, outbound := range options.Outbounds {
+		path := configPath("outbounds", i)
+		v.checkDialer(path, outbound.Options)
+		switch outboundOptions := outbound.Options.(type) {
+		case *option.SelectorOutboundOptions:
+			v.checkOutbounds(configPath(path, "outbounds"), outboundOptions.Outbounds)
+			if outboundOptions.Default != "" {
+				v.checkOutbound(configPath(path, "default"), outboundOptions.Default)
+			}
+		case *option.URLTestOutboundOptions:
+			v.checkOutbounds(configPath(path, "outbounds"), outboundOptions.Outbounds)
+		}
+	}
+	for i, inbound := range options.Inbounds {
+		v.checkDialer(configPath("inbounds", i), inbound.Options)
+	}
+	if options.DNS != nil {
+		for i, server := range options.DNS.Servers {
+			v.checkDialer(configPath("dns", "servers", i), server.Options)
+		}
+		for i, rule := range options.DNS.Rules {
+			v.checkDNSRule(configPath("dns", "rules", i), rule)
+		}
+		if options.DNS.Final != "" && !v.dnsServers[options.DNS.Final] {
+			v.report(ConfigDiagnosticError, configPath("dns", "final"), Current().UnknownDNSServerMessage, options.DNS.Final)
+		}
+	}
+	if options.Route != nil {
+		for i, ruleSet := range options.Route.RuleSet {
+			if ruleSet.Type == C.RuleSetTypeRemote && ruleSet.RemoteOptions.DownloadDetour != "" {
+				v.checkOutbound(configPath("route", "rule_set", i, "download_detour"), ruleSet.RemoteOptions.DownloadDetour)
+			}
+		}
+		for i, rule := range options.Route.Rules {
+			v.checkRule(configPath("route", "rules", i), rule)
+		}
+		if options.Route.Final != "" {
+			v.checkOutbound(configPath("route", "final"), options.Route.Final)
+		}
+		for i, ruleSet := range options.Route.RuleSet {
+			if !v.usedRuleSets[ruleSet.Tag] {
+				v.report(ConfigDiagnosticWarning, configPath("route", "rule_set", i, "tag"), Current().UnusedRuleSetMessage, ruleSet.Tag)
+			}
+		}
+	}
+}
+
+// defineTag records a tag, defaulting to the index like the service does.
+func (v *configValidator) defineTag(tags map[string]bool, path []configPathSegment, tag string, index int) {
+	if tag == "" {
+		tag = strconv.Itoa(index)
+	}
+	if tags[tag] {
+		v.report(ConfigDiagnosticError, path, Current().DuplicateTagMessage, tag)
+		return
+	}
+	tags[tag] = true
+}
+
+func (v *configValidator) checkDialer(path []configPathSegment, options any) {
+	dialerOptions, isDialer := options.(option.DialerOptionsWrapper)
+	if !isDialer {
+		return
+	}
+	detour := dialerOptions.TakeDialerOptions().Detour
+	if detour != "" {
+		v.checkOutbound(configPath(path, "detour"), detour)
+	}
+}
+
+func (v *configValidator) checkOutbounds(path []configPathSegment, tags []string) {
+	for i, tag := range tags {
+		v.checkOutbound(configPath(path, i), tag)
+	}
+}
+
+func (v *configValidator) checkOutbound(path []configPathSegment, tag string) {
+	if !v.outbounds[tag] {
+		v.report(ConfigDiagnosticError, path, Current().UnknownOutboundMessage, tag)
+	}
+}
+
+func (v *configValidator) checkRuleSets(path []configPathSegment, tags []string) {
+	for i, tag := range tags {
+		v.usedRuleSets[tag] = true
+		if v.ruleSets[tag] {
+			continue
+		}
+		elementPath := configPath(path, i)
+		if len(tags) == 1 {
+			elementPath = path
+		}
+		v.report(ConfigDiagnosticError, elementPath, Current().UnknownRuleSetMessage, tag)
+	}
+}
+
+func (v *configValidator) checkRule(path []configPathSegment, rule option.Rule) {
+	var action option.RuleAction
+	switch rule.Type {
+	case C.RuleTypeDefault:
+		v.checkRuleSets(configPath(path, "rule_set"), rule.DefaultOptions.RuleSet)
+		action = rule.DefaultOptions.RuleAction
+	case C.RuleTypeLogical:
+		for i, subRule := range rule.LogicalOptions.Rules {
+			v.checkRule(configPath(path, "rules", i), subRule)
+		}
+		action = rule.LogicalOptions.RuleAction
+	}
+	if action.Action == C.RuleActionTypeRoute && action.RouteOptions.Outbound != "" {
+		v.checkOutbound(configPath(path, "outbound"), action.RouteOptions.Outbound)
+	}
+}
+
+func (v *configValidator) checkDNSRule(path []configPathSegment, rule option.DNSRule) {
+	var action option.DNSRuleAction
+	switch rule.Type {
+	case C.RuleTypeDefault:
+		v.checkRuleSets(configPath(path, "rule_set"), rule.DefaultOptions.RuleSet)
+		action = rule.DefaultOptions.DNSRuleAction
+	case C.RuleTypeLogical:
+		for i, subRule := range rule.LogicalOptions.Rules {
+			v.checkDNSRule(configPath(path, "rules", i), subRule)
+		}
+		action = rule.LogicalOptions.DNSRuleAction
+	}
+	if action.Action == C.RuleActionTypeRoute && action.RouteOptions.Server != "" && !v.dnsServers[action.RouteOptions.Server] {
+		v.report(ConfigDiagnosticError, configPath(path, "server"), Current().UnknownDNSServerMessage, action.RouteOptions.Server)
+	}
+}
@@ -0,0 +1,156 @@
+package liboc
+
+import (
+	"context"
+	"net/netip"
+	"time"
+
+	"github.com/sagernet/sing-box/common/conntrack"
+	"github.com/sagernet/sing-box/common/process"
+	"github.com/sagernet/sing-box/experimental/clashapi"
+	"github.com/sagernet/sing-box/experimental/clashapi/trafficontrol"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+func (c *Connection) setProcessInfo(processInfo *process.Info) {
+	c.ProcessID = int32(processInfo.ProcessID)
+	c.ProcessPath = processInfo.ProcessPath
+	c.PackageName = processInfo.PackageName
+	c.UserID = processInfo.UserId
+}
+
+func (s *BoxService) trafficManager() (*trafficontrol.Manager, error) {
+	clashServer, loaded := s.current().clashServer.(*clashapi.Server)
+	if !loaded {
+		return nil, E.New("clash api not available")
+	}
+	return clashServer.TrafficManager(), nil
+}
+
+// Connections returns the active connections. Process information is looked
+// up through the platform for connections routed without it.
+func (s *BoxService) Connections() (ConnectionIterator, error) {
+	connections, err := s.listConnections(make(map[uuid.UUID]*Connection), false)
+	if err != nil {
+		return nil, err
+	}
+	return newPtrIterator(connections), nil
+}
+
+type ConnectionListener interface {
+	WriteConnections(connections ConnectionIterator)
+}
+
+type ConnectionSubscription struct {
+	cancel context.CancelFunc
+}
+
+func (s *ConnectionSubscription) Close() {
+	s.cancel()
+}
+
+// SubscribeConnections pushes the active connections to listener every
+// intervalMs milliseconds (one second if not positive) until the
+// subscription or the service is closed. Uplink and downlink are measured
+// over each interval, and processes are looked up once per connection.
+func (s *BoxService) SubscribeConnections(listener ConnectionListener, intervalMs int32) *ConnectionSubscription {
+	interval := time.Second
+	if intervalMs > 0 {
+		interval = time.Duration(intervalMs) * time.Millisecond
+	}
+	ctx, cancel := context.WithCancel(s.trafficTracker.ctx)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		connectionMap := make(map[uuid.UUID]*Connection)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			connections, err := s.listConnections(connectionMap, false)
+			if err != nil {
+				return
+			}
+			listener.WriteConnections(newPtrIterator(connections))
+		}
+	}()
+	return &ConnectionSubscription{cancel}
+}
+
+// listConnections returns the active connections of the current instance,
+// followed by the recently closed ones if includeClosed is set. Entries in
+// connectionMap are updated in place, so a stream reusing the map reports
+// per-interval traffic and looks up each process only once. Entries no
+// longer listed, such as closed connections dropped from the bounded closed
+// list after being reported, are evicted.
+func (s *BoxService) listConnections(connectionMap map[uuid.UUID]*Connection, includeClosed bool) ([]Connection, error) {
+	trafficManager, err := s.trafficManager()
+	if err != nil {
+		return nil, err
+	}
+	state := s.current()
+	var connections []Connection
+	listed := make(map[uuid.UUID]bool)
+	for _, metadata := range trafficManager.Connections() {
+		listed[metadata.ID] = true
+		_, known := connectionMap[metadata.ID]
+		connection := newConnection(connectionMap, metadata, false)
+		if !known && metadata.Metadata.ProcessInfo == nil {
+			source := metadata.Metadata.Source
+			destination := metadata.Metadata.Destination
+			if source.IsIP() && destination.IsIP() {
+				processInfo, _ := state.platformWrapper.FindProcessInfo(state.ctx, metadata.Metadata.Network, netip.AddrPortFrom(source.Addr, source.Port), netip.AddrPortFrom(destination.Addr, destination.Port))
+				if processInfo != nil {
+					connection.setProcessInfo(processInfo)
+					connectionMap[metadata.ID].setProcessInfo(processInfo)
+				}
+			}
+		}
+		connections = append(connections, connection)
+	}
+	if includeClosed {
+		for _, metadata := range trafficManager.ClosedConnections() {
+			listed[metadata.ID] = true
+			connections = append(connections, newConnection(connectionMap, metadata, true))
+		}
+	}
+	for id := range connectionMap {
+		if !listed[id] {
+			delete(connectionMap, id)
+		}
+	}
+	return connections, nil
+}
+
+func (s *BoxService) CloseConnection(id string) error {
+	trafficManager, err := s.trafficManager()
+	if err != nil {
+		return err
+	}
+	connectionID, err := uuid.FromString(id)
+	if err != nil {
+		return E.Cause(err, "invalid connection id: ", id)
+	}
+	targetConn := trafficManager.Connection(connectionID)
+	if targetConn == nil {
+		return E.New("connection already closed")
+	}
+	return targetConn.Close()
+}
+
+// CloseAllConnections closes every routed connection, and every tracked
+// connection when built with the with_conntrack tag.
+func (s *BoxService) CloseAllConnections() {
+	if trafficManager, err := s.trafficManager(); err == nil {
+		for _, metadata := range trafficManager.Connections() {
+			if targetConn := trafficManager.Connection(metadata.ID); targetConn != nil {
+				targetConn.Close()
+			}
+		}
+	}
+	conntrack.Close()
+}
@@ -0,0 +1,137 @@
+package liboc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+const crashReportSuffix = ".json"
+
+type CrashReport struct {
+	ID         string `json:"id"`
+	Source     string `json:"source"`
+	Error      string `json:"error"`
+	Stack      string `json:"stack"`
+	Version    string `json:"version"`
+	Timestamp  int64  `json:"timestamp"`
+	ConfigHash string `json:"config_hash,omitempty"`
+}
+
+func crashReportDir() string {
+	if sWorkingPath == "" {
+		return ""
+	}
+	return filepath.Join(sWorkingPath, "crash_reports")
+}
+
+// catchPanic must be deferred directly. It turns a panic into a crash report
+// and, if errPtr is not nil, into the error returned by the caller.
+func catchPanic(source string, configContent string, errPtr *error) {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+	err := E.New("panic in ", source, ": ", recovered)
+	report := &CrashReport{
+		Source:    source,
+		Error:     err.Error(),
+		Stack:     string(debug.Stack()),
+		Version:   C.Version,
+		Timestamp: time.Now().UnixMilli(),
+	}
+	if configContent != "" {
+		configHash := sha256.Sum256([]byte(configContent))
+		report.ConfigHash = hex.EncodeToString(configHash[:])
+	}
+	writeErr := writeCrashReport(report)
+	if writeErr != nil {
+		log.Error(E.Cause(writeErr, "write crash report"))
+	}
+	if errPtr != nil {
+		*errPtr = err
+	} else {
+		log.Error(err)
+	}
+}
+
+func writeCrashReport(report *CrashReport) error {
+	reportDir := crashReportDir()
+	if reportDir == "" {
+		return os.ErrInvalid
+	}
+	err := os.MkdirAll(reportDir, 0o700)
+	if err != nil {
+		return err
+	}
+	report.ID = strconv.FormatInt(report.Timestamp, 10)
+	for i := 1; ; i++ {
+		_, err = os.Stat(filepath.Join(reportDir, report.ID+crashReportSuffix))
+		if os.IsNotExist(err) {
+			break
+		}
+		report.ID = strconv.FormatInt(report.Timestamp, 10) + "-" + strconv.Itoa(i)
+	}
+	content, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(reportDir, report.ID+crashReportSuffix), content, 0644)
+}
+
+// ListCrashReports returns the IDs of saved crash reports, newest first.
+func ListCrashReports() (StringIterator, error) {
+	reportDir := crashReportDir()
+	if reportDir == "" {
+		return nil, os.ErrInvalid
+	}
+	entries, err := os.ReadDir(reportDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return newIterator([]string{}), nil
+		}
+		return nil, err
+	}
+	var reportIDs []string
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), crashReportSuffix) {
+			continue
+		}
+		reportIDs = append(reportIDs, strings.TrimSuffix(entry.Name(), crashReportSuffix))
+	}
+	slices.SortFunc(reportIDs, func(a, b string) int {
+		return strings.Compare(b, a)
+	})
+	return newIterator(reportIDs), nil
+}
+
+func ReadCrashReport(id string) (*CrashReport, error) {
+	reportDir := crashReportDir()
+	if reportDir == "" {
+		return nil, os.ErrInvalid
+	}
+	if id == "" || strings.ContainsAny(id, `/\`) || strings.Contains(id, "..") {
+		return nil, E.New("invalid crash report id: ", id)
+	}
+	content, err := os.ReadFile(filepath.Join(reportDir, id+crashReportSuffix))
+	if err != nil {
+		return nil, err
+	}
+	var report CrashReport
+	err = json.Unmarshal(content, &report)
+	if err != nil {
+		return nil, E.Cause(err, "parse crash report")
+	}
+	return &report, nil
+}
@@ -1,5 +1,18 @@
 package liboc
 
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/experimental/deprecated"
+)
+
+var _ = deprecated.Note(DeprecatedNote{})
+
 type DeprecatedNote struct {
 	Name              string
 	Description       string
@@ -9,11 +22,111 @@ type DeprecatedNote struct {
 	MigrationLink     string
 }
 
+func (n DeprecatedNote) Impending() bool {
+	return deprecated.Note(n).Impending()
+}
+
+// Message formats the note with the current Locale.
+func (n DeprecatedNote) Message() string {
+	if n.MigrationLink != "" {
+		return fmt.Sprintf(Current().DeprecatedMessage, n.Description, n.DeprecatedVersion, n.ScheduledVersion, n.MigrationLink)
+	} else {
+		return fmt.Sprintf(Current().DeprecatedMessageNoLink, n.Description, n.DeprecatedVersion, n.ScheduledVersion)
+	}
+}
+
+// Deprecated: deprecated features are collected by the service; use
+// BoxService.DeprecatedNotes or CommandClient.GetDeprecatedNotes instead.
+// Implementations are never called.
 type DeprecatedManager interface {
 	ReportDeprecated(feature DeprecatedNote)
 }
 
-type deprecatedManager struct{}
+type DeprecatedNoteIterator interface {
+	Next() *DeprecatedNote
+	HasNext() bool
+}
+
+var _ deprecated.Manager = (*deprecatedManager)(nil)
+
+// deprecatedManager collects the deprecated features reported while a
+// service is created and running.
+type deprecatedManager struct {
+	access            sync.Mutex
+	platformInterface PlatformInterface
+	notes             []DeprecatedNote
+}
 
-func (m *deprecatedManager) ReportDeprecated(feature DeprecatedNote) {
-}
\ No newline at end of file
+func (m *deprecatedManager) ReportDeprecated(feature deprecated.Note) {
+	m.access.Lock()
+	defer m.access.Unlock()
+	for _, note := range m.notes {
+		if note.Name == feature.Name {
+			return
+		}
+	}
+	note := DeprecatedNote(feature)
+	m.notes = append(m.notes, note)
+	if sNotifyDeprecated && m.platformInterface != nil {
+		go notifyDeprecated(m.platformInterface, note)
+	}
+}
+
+func (m *deprecatedManager) Notes() []DeprecatedNote {
+	m.access.Lock()
+	defer m.access.Unlock()
+	return append([]DeprecatedNote(nil), m.notes...)
+}
+
+// DeprecatedNotes returns the deprecated features used by the current
+// configuration.
+func (s *BoxService) DeprecatedNotes() DeprecatedNoteIterator {
+	return newPtrIterator(s.current().deprecatedManager.Notes())
+}
+
+var deprecatedNotifiedAccess sync.Mutex
+
+func deprecatedNotifiedPath() string {
+	if sWorkingPath == "" {
+		return ""
+	}
+	return filepath.Join(sWorkingPath, "deprecated_notified.json")
+}
+
+// notifyDeprecated sends a notification for note unless one was already sent
+// for the feature by the same app version.
+func notifyDeprecated(platformInterface PlatformInterface, note DeprecatedNote) {
+	notifiedPath := deprecatedNotifiedPath()
+	if notifiedPath == "" {
+		return
+	}
+	appVersion := sAppVersion
+	if appVersion == "" {
+		appVersion = C.Version
+	}
+	deprecatedNotifiedAccess.Lock()
+	defer deprecatedNotifiedAccess.Unlock()
+	notified := make(map[string]string)
+	content, err := os.ReadFile(notifiedPath)
+	if err == nil {
+		json.Unmarshal(content, &notified)
+	}
+	if notified[note.Name] == appVersion {
+		return
+	}
+	err = platformInterface.SendNotification(&Notification{
+		Identifier: "deprecated-" + note.Name,
+		TypeName:   "deprecated",
+		Title:      Current().DeprecatedNotificationTitle,
+		Body:       note.Message(),
+		OpenURL:    note.MigrationLink,
+	})
+	if err != nil {
+		return
+	}
+	notified[note.Name] = appVersion
+	content, err = json.Marshal(notified)
+	if err == nil {
+		os.WriteFile(notifiedPath, content, 0o644)
+	}
+}
@@ -0,0 +1,29 @@
+package liboc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDeprecatedNoteMessage(t *testing.T) {
+	note := DeprecatedNote{
+		Description:       "legacy option",
+		DeprecatedVersion: "1.1.0",
+		ScheduledVersion:  "1.3.0",
+	}
+	message := note.Message()
+	if !strings.HasPrefix(message, "legacy option") || strings.Contains(message, "%!") {
+		t.Fatalf("unexpected message without link: %s", message)
+	}
+	note.MigrationLink = "https://example.com/migration"
+	message = note.Message()
+	if !strings.HasSuffix(message, note.MigrationLink) || strings.Contains(message, "%!") {
+		t.Fatalf("unexpected message with link: %s", message)
+	}
+	for _, locale := range []string{"es", "fa", "ru", "zh_CN"} {
+		localeMessage := localeRegistry[locale].DeprecatedMessage
+		if strings.Count(localeMessage, "%s") != 4 {
+			t.Errorf("locale %s deprecated message does not take a link: %s", locale, localeMessage)
+		}
+	}
+}
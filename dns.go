@@ -2,18 +2,26 @@ package liboc
 
 import (
 	"context"
+	"net/netip"
+	"os"
+	"strings"
+	"syscall"
 
 	mDNS "github.com/miekg/dns"
 	"github.com/sagernet/sing-box/adapter"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/dns"
 	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	"github.com/sagernet/sing/common/task"
 )
 
 type ExchangeContext struct {
 	context   context.Context
-	message   interface{}
-	addresses interface{}
+	message   *mDNS.Msg
+	addresses []netip.Addr
+	rcode     int
 	error     error
 }
 
@@ -24,6 +32,45 @@ func (c *ExchangeContext) OnCancel(callback Func) {
 	}()
 }
 
+// RawSuccess sets the packed DNS response returned by the platform resolver.
+func (c *ExchangeContext) RawSuccess(result []byte) {
+	message := new(mDNS.Msg)
+	err := message.Unpack(result)
+	if err != nil {
+		c.error = E.Cause(err, "parse response")
+		return
+	}
+	c.message = message
+}
+
+// Success sets the addresses resolved by the platform resolver.
+func (c *ExchangeContext) Success(result StringIterator) {
+	c.addresses = nil
+	for _, addressString := range iteratorToArray[string](result) {
+		address := M.ParseSocksaddrHostPort(addressString, 0).Unwrap().Addr
+		if address.IsValid() {
+			c.addresses = append(c.addresses, address)
+		}
+	}
+}
+
+// ErrorCode reports a DNS rcode such as NXDOMAIN or SERVFAIL.
+func (c *ExchangeContext) ErrorCode(code int32) {
+	c.rcode = int(code)
+}
+
+// ErrnoCode reports a system errno from the platform resolver.
+func (c *ExchangeContext) ErrnoCode(code int32) {
+	switch errno := syscall.Errno(code); errno {
+	case syscall.ECANCELED:
+		c.error = context.Canceled
+	case syscall.ETIMEDOUT:
+		c.error = os.ErrDeadlineExceeded
+	default:
+		c.error = E.Cause(errno, "platform resolver")
+	}
+}
+
 type LocalDNSTransport interface {
 	Raw() bool
 	Lookup(ctx *ExchangeContext, network string, domain string) error
@@ -56,42 +103,69 @@ func (p *platformTransport) Close() error {
 
 func (p *platformTransport) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
 	if p.iif == nil {
-
-		response := new(mDNS.Msg)
-		response.SetReply(message)
-		response.Rcode = mDNS.RcodeNameError
-		return response, nil
-	}
-
-	msgBytes, err := message.Pack()
-	if err != nil {
-		return nil, err
+		return dns.FixedResponseStatus(message, mDNS.RcodeNameError), nil
 	}
-
 	exchCtx := &ExchangeContext{
 		context: ctx,
 	}
-
-	err = p.iif.Exchange(exchCtx, msgBytes)
+	if p.iif.Raw() {
+		msgBytes, err := message.Pack()
+		if err != nil {
+			return nil, err
+		}
+		err = runPlatformResolver(ctx, func() error {
+			return p.iif.Exchange(exchCtx, msgBytes)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return exchCtx.response(message, false)
+	}
+	if len(message.Question) == 0 {
+		return dns.FixedResponseStatus(message, mDNS.RcodeFormatError), nil
+	}
+	question := message.Question[0]
+	var network string
+	switch question.Qtype {
+	case mDNS.TypeA:
+		network = "ip4"
+	case mDNS.TypeAAAA:
+		network = "ip6"
+	default:
+		return dns.FixedResponseStatus(message, mDNS.RcodeNotImplemented), nil
+	}
+	err := runPlatformResolver(ctx, func() error {
+		return p.iif.Lookup(exchCtx, network, strings.TrimSuffix(question.Name, "."))
+	})
 	if err != nil {
 		return nil, err
 	}
+	return exchCtx.response(message, true)
+}
 
-	if exchCtx.error != nil {
-		return nil, exchCtx.error
-	}
+// runPlatformResolver calls into the platform resolver and stops waiting for
+// it once ctx is done; the platform is told through ExchangeContext.OnCancel.
+func runPlatformResolver(ctx context.Context, resolve func() error) error {
+	var group task.Group
+	group.Append0(func(ctx context.Context) error {
+		return resolve()
+	})
+	return group.Run(ctx)
+}
 
-	if respBytes, ok := exchCtx.message.([]byte); ok {
-		response := new(mDNS.Msg)
-		err = response.Unpack(respBytes)
-		if err != nil {
-			return nil, err
-		}
-		return response, nil
+func (c *ExchangeContext) response(request *mDNS.Msg, lookup bool) (*mDNS.Msg, error) {
+	if c.error != nil {
+		return nil, c.error
 	}
-
-	response := new(mDNS.Msg)
-	response.SetReply(message)
-	response.Rcode = mDNS.RcodeNameError
-	return response, nil
+	if c.rcode != mDNS.RcodeSuccess {
+		return dns.FixedResponseStatus(request, c.rcode), nil
+	}
+	if c.message != nil {
+		c.message.Id = request.Id
+		return c.message, nil
+	}
+	if (lookup || len(c.addresses) > 0) && len(request.Question) > 0 {
+		return dns.FixedResponse(request.Id, request.Question[0], c.addresses, C.DefaultDNSTTL), nil
+	}
+	return dns.FixedResponseStatus(request, mDNS.RcodeNameError), nil
 }
@@ -0,0 +1,20 @@
+#!/bin/sh
+# Builds the liboc shared library for the host OS into $1 (default: build)
+# and refreshes the checked-in C header in export/include. Build tags are
+# taken from $TAGS; services need at least with_clash_api.
+set -e
+
+cd "$(dirname "$0")/.."
+OUTPUT="${1:-build}"
+TAGS="${TAGS:-with_clash_api}"
+
+case "$(go env GOOS)" in
+windows) LIBRARY=liboc.dll ;;
+darwin) LIBRARY=liboc.dylib ;;
+*) LIBRARY=liboc.so ;;
+esac
+
+mkdir -p "$OUTPUT"
+CGO_ENABLED=1 go build -tags "$TAGS" -trimpath -buildmode=c-shared -ldflags "-s -w" -o "$OUTPUT/$LIBRARY" ./export
+cp "$OUTPUT/liboc.h" export/include/liboc.h
+echo "$OUTPUT/$LIBRARY"
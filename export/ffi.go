@@ -1,22 +1,102 @@
-//go:build windows && !android
+//go:build (windows || linux || darwin) && !android && !ios && cgo
 package main
 /*
 #include <stdlib.h>
 #include <stdint.h>
 #include <stdbool.h>
-// Callback function types for PlatformInterface
+#include <string.h>
+// Exports that can fail return a LibocError, or NULL on success. The caller
+// owns the returned error and releases it with LibocErrorFree; its message
+// and causes are read with LibocErrorCode, LibocErrorMessage and
+// LibocErrorCause. Error codes are stable across releases.
+#define LIBOC_OK 0
+#define LIBOC_ERROR_UNKNOWN 1
+#define LIBOC_ERROR_INVALID_ARGUMENT 2
+#define LIBOC_ERROR_CONFIG 3
+#define LIBOC_ERROR_NOT_FOUND 4
+#define LIBOC_ERROR_TIMEOUT 5
+#define LIBOC_ERROR_PERMISSION 6
+#define LIBOC_ERROR_PLATFORM 7
+typedef struct LibocError {
+    int32_t code;
+    char* message;
+    struct LibocError* cause;
+} LibocError;
+// Receives the events of a LibocSubscribe subscription from a liboc thread.
+// eventJSON is only valid during the call. An event already being delivered
+// may still arrive after LibocUnsubscribe returns.
+typedef void (*SubscribeFunc)(int64_t subscriptionID, const char* eventJSON, void* userData);
+static inline void call_subscribe(SubscribeFunc func, int64_t subscriptionID, const char* eventJSON, void* userData) {
+    func(subscriptionID, eventJSON, userData);
+}
+// PlatformInterface is versioned by its leading size and version fields:
+// callers set size to sizeof(PlatformInterface) and version to
+// LIBOC_PLATFORM_INTERFACE_VERSION as compiled, and liboc ignores callbacks
+// beyond the given size. NewService rejects a version of 0 or one newer than
+// the library with LIBOC_ERROR_INVALID_ARGUMENT. Any callback may be NULL to keep the default
+// behavior. Strings returned by callbacks must be allocated with malloc and
+// are freed by liboc; returned char* errors are NULL on success.
+#define LIBOC_PLATFORM_INTERFACE_VERSION 1
 typedef void (*WriteLogFunc)(const char* message);
 typedef int32_t (*FindConnectionOwnerFunc)(int32_t ipProtocol, const char* sourceAddress, int32_t sourcePort, const char* destinationAddress, int32_t destinationPort);
 typedef char* (*PackageNameByUidFunc)(int32_t uid);
 typedef int32_t (*UidByPackageNameFunc)(const char* packageName);
-typedef void (*InterfaceUpdateFunc)(const char* interfaceName, int32_t interfaceIndex, int32_t isExpensive, int32_t isConstrained);
+typedef void (*InterfaceUpdateFunc)(int64_t monitorID, char* interfaceName, int32_t interfaceIndex, int32_t isExpensive, int32_t isConstrained);
+typedef int32_t (*BoolFunc)(void);
+typedef char* (*AutoDetectInterfaceControlFunc)(int32_t fd);
+// optionsJSON describes the tun interface; the returned value is the tun
+// file descriptor or handle, or -1 with errorOut set. If openTun is NULL,
+// liboc creates the device itself with sing-tun on Windows and Linux, and
+// uses the utun descriptor opened by the network extension on macOS.
+typedef int32_t (*OpenTunFunc)(const char* optionsJSON, char** errorOut);
+// The host reports default interface changes by calling update with
+// monitorID until closeDefaultInterfaceMonitor is called for it. If
+// startDefaultInterfaceMonitor is NULL, liboc watches the routing table
+// itself.
+typedef char* (*StartDefaultInterfaceMonitorFunc)(int64_t monitorID, InterfaceUpdateFunc update);
+typedef char* (*CloseDefaultInterfaceMonitorFunc)(int64_t monitorID);
+// Returns a JSON array of interfaces, or NULL with errorOut set.
+typedef char* (*GetInterfacesFunc)(char** errorOut);
+typedef int32_t (*ReadWIFIStateFunc)(char** ssidOut, char** bssidOut);
+// Returns a JSON array of PEM encoded certificates.
+typedef char* (*SystemCertificatesFunc)(void);
+typedef void (*ClearDNSCacheFunc)(void);
 typedef struct {
+    const char* identifier;
+    const char* typeName;
+    int32_t typeID;
+    const char* title;
+    const char* subtitle;
+    const char* body;
+    const char* openURL;
+} Notification;
+typedef char* (*SendNotificationFunc)(const Notification* notification);
+// Resolves domain for network "ip", "ip4" or "ip6", setting addressesOut to
+// newline separated addresses or rcodeOut to a DNS rcode.
+typedef char* (*LookupFunc)(const char* network, const char* domain, char** addressesOut, int32_t* rcodeOut);
+typedef struct {
+    uint32_t size;
+    uint32_t version;
     WriteLogFunc writeLog;
     FindConnectionOwnerFunc findConnectionOwner;
     PackageNameByUidFunc packageNameByUid;
     UidByPackageNameFunc uidByPackageName;
-    InterfaceUpdateFunc interfaceUpdate;
+    BoolFunc usePlatformAutoDetectInterfaceControl;
+    AutoDetectInterfaceControlFunc autoDetectInterfaceControl;
+    OpenTunFunc openTun;
+    BoolFunc useProcFS;
+    StartDefaultInterfaceMonitorFunc startDefaultInterfaceMonitor;
+    CloseDefaultInterfaceMonitorFunc closeDefaultInterfaceMonitor;
+    GetInterfacesFunc getInterfaces;
+    BoolFunc underNetworkExtension;
+    BoolFunc includeAllNetworks;
+    ReadWIFIStateFunc readWIFIState;
+    SystemCertificatesFunc systemCertificates;
+    ClearDNSCacheFunc clearDNSCache;
+    SendNotificationFunc sendNotification;
+    LookupFunc lookup;
 } PlatformInterface;
+extern void libocInterfaceUpdate(int64_t monitorID, char* interfaceName, int32_t interfaceIndex, int32_t isExpensive, int32_t isConstrained);
 static inline void call_writeLog(WriteLogFunc func, const char* message) {
     if (func != NULL) {
         func(message);
@@ -40,39 +120,163 @@ static inline int32_t call_uidByPackageName(UidByPackageNameFunc func, const cha
     }
     return -1;
 }
+static inline int32_t call_bool(BoolFunc func, int32_t defaultValue) {
+    if (func != NULL) {
+        return func();
+    }
+    return defaultValue;
+}
+static inline char* call_autoDetectInterfaceControl(AutoDetectInterfaceControlFunc func, int32_t fd) {
+    return func(fd);
+}
+static inline int32_t call_openTun(OpenTunFunc func, const char* optionsJSON, char** errorOut) {
+    return func(optionsJSON, errorOut);
+}
+static inline char* call_startDefaultInterfaceMonitor(StartDefaultInterfaceMonitorFunc func, int64_t monitorID) {
+    return func(monitorID, libocInterfaceUpdate);
+}
+static inline char* call_closeDefaultInterfaceMonitor(CloseDefaultInterfaceMonitorFunc func, int64_t monitorID) {
+    return func(monitorID);
+}
+static inline char* call_getInterfaces(GetInterfacesFunc func, char** errorOut) {
+    return func(errorOut);
+}
+static inline int32_t call_readWIFIState(ReadWIFIStateFunc func, char** ssidOut, char** bssidOut) {
+    return func(ssidOut, bssidOut);
+}
+static inline char* call_systemCertificates(SystemCertificatesFunc func) {
+    return func();
+}
+static inline void call_clearDNSCache(ClearDNSCacheFunc func) {
+    func();
+}
+static inline char* call_sendNotification(SendNotificationFunc func, const Notification* notification) {
+    return func(notification);
+}
+static inline char* call_lookup(LookupFunc func, const char* network, const char* domain, char** addressesOut, int32_t* rcodeOut) {
+    return func(network, domain, addressesOut, rcodeOut);
+}
 */
 import "C"
 import (
-	"bytes"
+	"cmp"
 	"context"
+	"encoding/json"
+	"errors"
 	"net"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"unsafe"
 	liboc "github.com/Open-Application/OpenCore"
-	"github.com/sagernet/sing-box"
 	"github.com/sagernet/sing-box/constant"
-	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing-tun"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/control"
 	E "github.com/sagernet/sing/common/exceptions"
-	"github.com/sagernet/sing/common/json"
+	"github.com/sagernet/sing/common/logger"
 )
 var (
-	serviceRegistry sync.Map
-	nextServiceID   int64    = 1
-	serviceMutex    sync.Mutex
+	serviceRegistry  sync.Map
+	nextServiceID    int64 = 1
+	serviceMutex     sync.Mutex
 	platformRegistry sync.Map
-	lastError     string
-	lastErrorLock sync.Mutex
 )
-func setLastError(err string) {
-	lastErrorLock.Lock()
-	defer lastErrorLock.Unlock()
-	lastError = err
+// callbackError is an error reported by a PlatformInterface callback.
+type callbackError struct {
+	message string
+}
+func (e *callbackError) Error() string {
+	return e.message
+}
+func errorCode(err error, defaultCode C.int32_t) C.int32_t {
+	var (
+		configError       *liboc.ConfigError
+		closeTimeoutError *liboc.CloseTimeoutError
+		platformError     *callbackError
+		categoryError     *kindError
+	)
+	if errors.As(err, &categoryError) {
+		switch categoryError.kind {
+		case errorKindInvalidArgument:
+			return C.LIBOC_ERROR_INVALID_ARGUMENT
+		case errorKindNotFound:
+			return C.LIBOC_ERROR_NOT_FOUND
+		case errorKindConfig:
+			return C.LIBOC_ERROR_CONFIG
+		}
+	}
+	switch {
+	case errors.As(err, &configError):
+		return C.LIBOC_ERROR_CONFIG
+	case errors.As(err, &closeTimeoutError), errors.Is(err, os.ErrDeadlineExceeded), errors.Is(err, context.DeadlineExceeded):
+		return C.LIBOC_ERROR_TIMEOUT
+	case errors.As(err, &platformError):
+		return C.LIBOC_ERROR_PLATFORM
+	case errors.Is(err, os.ErrPermission):
+		return C.LIBOC_ERROR_PERMISSION
+	case errors.Is(err, os.ErrNotExist):
+		return C.LIBOC_ERROR_NOT_FOUND
+	}
+	return defaultCode
+}
+// newError converts err and its chain of causes into a LibocError, using
+// defaultCode where no more specific code applies.
+func newError(err error, defaultCode C.int32_t) *C.LibocError {
+	if err == nil {
+		return nil
+	}
+	cError := (*C.LibocError)(C.calloc(1, C.size_t(unsafe.Sizeof(C.LibocError{}))))
+	cError.code = errorCode(err, defaultCode)
+	cError.message = C.CString(err.Error())
+	cause := errors.Unwrap(err)
+	for cause != nil && cause.Error() == err.Error() {
+		cause = errors.Unwrap(cause)
+	}
+	if cause != nil {
+		cError.cause = newError(cause, cError.code)
+	}
+	return cError
 }
-func clearLastError() {
-	lastErrorLock.Lock()
-	defer lastErrorLock.Unlock()
-	lastError = ""
+func newErrorMessage(code C.int32_t, message string) *C.LibocError {
+	return newError(errors.New(message), code)
+}
+//export LibocErrorCode
+func LibocErrorCode(err *C.LibocError) C.int32_t {
+	if err == nil {
+		return C.LIBOC_OK
+	}
+	return err.code
+}
+// LibocErrorMessage returns the message of err, which is owned by err.
+//export LibocErrorMessage
+func LibocErrorMessage(err *C.LibocError) *C.char {
+	if err == nil {
+		return nil
+	}
+	return err.message
+}
+// LibocErrorCause returns the error that caused err, which is owned by err.
+//export LibocErrorCause
+func LibocErrorCause(err *C.LibocError) *C.LibocError {
+	if err == nil {
+		return nil
+	}
+	return err.cause
+}
+// LibocErrorFree frees err together with its causes.
+//export LibocErrorFree
+func LibocErrorFree(err *C.LibocError) {
+	for err != nil {
+		cause := err.cause
+		C.free(unsafe.Pointer(err.message))
+		C.free(unsafe.Pointer(err))
+		err = cause
+	}
 }
 //export FreeString
 func FreeString(str *C.char) {
@@ -87,21 +291,27 @@ func FreeBytes(data *C.char) {
 	}
 }
 //export Setup
-func Setup(basePath *C.char, workingPath *C.char, tempPath *C.char, isTVOS C.int, fixAndroidStack C.int) *C.char {
+func Setup(basePath *C.char, workingPath *C.char, tempPath *C.char, isTVOS C.int, fixAndroidStack C.int) *C.LibocError {
 	if basePath == nil || workingPath == nil || tempPath == nil {
-		return C.CString("path parameters cannot be null")
+		return newErrorMessage(C.LIBOC_ERROR_INVALID_ARGUMENT, "path parameters cannot be null")
 	}
 	err := liboc.Setup(&liboc.SetupOptions{
-		BasePath:        C.GoString(basePath),
-		WorkingPath:     C.GoString(workingPath),
-		TempPath:        C.GoString(tempPath),
-		IsTVOS:          isTVOS != 0,
-		FixAndroidStack: fixAndroidStack != 0,
+		BasePath:           C.GoString(basePath),
+		WorkingPath:        C.GoString(workingPath),
+		TempPath:           C.GoString(tempPath),
+		IsTVOS:             isTVOS != 0,
+		FixAndroidStack:    fixAndroidStack != 0,
+		ExitOnCloseTimeout: exitOnCloseTimeout.Load(),
 	})
-	if err != nil {
-		return C.CString(err.Error())
-	}
-	return nil
+	return newError(err, C.LIBOC_ERROR_UNKNOWN)
+}
+var exitOnCloseTimeout atomic.Bool
+// SetExitOnCloseTimeout makes closing a service exit the process when it
+// exceeds its timeout. It may be called before or after Setup.
+//export SetExitOnCloseTimeout
+func SetExitOnCloseTimeout(enabled C.int) {
+	exitOnCloseTimeout.Store(enabled != 0)
+	liboc.SetExitOnCloseTimeout(enabled != 0)
 }
 //export SetMemoryLimit
 func SetMemoryLimit(enabled C.int) {
@@ -116,10 +326,13 @@ func ClearServiceError() {
 	liboc.ClearServiceError()
 }
 //export ReadServiceError
-func ReadServiceError(errorOut **C.char) *C.char {
+func ReadServiceError(errorOut **C.char) *C.LibocError {
+	if errorOut == nil {
+		return newErrorMessage(C.LIBOC_ERROR_INVALID_ARGUMENT, "errorOut is null")
+	}
 	stringBox, err := liboc.ReadServiceError()
 	if err != nil {
-		return C.CString(err.Error())
+		return newError(err, C.LIBOC_ERROR_UNKNOWN)
 	}
 	if stringBox != nil {
 		*errorOut = C.CString(stringBox.Value)
@@ -127,247 +340,466 @@ func ReadServiceError(errorOut **C.char) *C.char {
 	return nil
 }
 //export WriteServiceError
-func WriteServiceError(message *C.char) *C.char {
-	err := liboc.WriteServiceError(C.GoString(message))
-	if err != nil {
-		return C.CString(err.Error())
-	}
-	return nil
+func WriteServiceError(message *C.char) *C.LibocError {
+	return newError(liboc.WriteServiceError(C.GoString(message)), C.LIBOC_ERROR_UNKNOWN)
 }
 //export RedirectStderr
-func RedirectStderr(path *C.char) *C.char {
-	err := liboc.RedirectStderr(C.GoString(path))
-	if err != nil {
-		return C.CString(err.Error())
-	}
-	return nil
+func RedirectStderr(path *C.char) *C.LibocError {
+	return newError(liboc.RedirectStderr(C.GoString(path)), C.LIBOC_ERROR_UNKNOWN)
+}
+//export EnableLogFile
+func EnableLogFile(maxSizeBytes C.int64_t, maxAgeDays C.int32_t, maxFiles C.int32_t, compress C.int) *C.LibocError {
+	err := liboc.EnableLogFile(&liboc.LogFileOptions{
+		MaxSizeBytes: int64(maxSizeBytes),
+		MaxAgeDays:   int32(maxAgeDays),
+		MaxFiles:     int32(maxFiles),
+		Compress:     compress != 0,
+	})
+	return newError(err, C.LIBOC_ERROR_UNKNOWN)
+}
+//export DisableLogFile
+func DisableLogFile() {
+	liboc.DisableLogFile()
+}
+//export ExportLogFiles
+func ExportLogFiles(path *C.char) *C.LibocError {
+	return newError(liboc.ExportLogFiles(C.GoString(path)), C.LIBOC_ERROR_UNKNOWN)
+}
+//export ClearLogFiles
+func ClearLogFiles() *C.LibocError {
+	return newError(liboc.ClearLogFiles(), C.LIBOC_ERROR_UNKNOWN)
 }
 //export Version
 func Version() *C.char {
 	return C.CString(constant.Version)
 }
 //export CheckConfig
-func CheckConfig(configContent *C.char) *C.char {
+func CheckConfig(configContent *C.char) *C.LibocError {
 	if configContent == nil {
-		return C.CString("configContent is null")
-	}
-	ctx := liboc.BaseContext(nil)
-	options, err := parseConfig(ctx, C.GoString(configContent))
-	if err != nil {
-		return C.CString(err.Error())
+		return newErrorMessage(C.LIBOC_ERROR_INVALID_ARGUMENT, "configContent is null")
 	}
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
-	instance, err := box.New(box.Options{
-		Context: ctx,
-		Options: options,
-	})
+	err := liboc.CheckConfig(C.GoString(configContent))
 	if err != nil {
-		return C.CString(err.Error())
+		return newError(err, C.LIBOC_ERROR_CONFIG)
 	}
-	instance.Close()
 	return nil
 }
 //export FormatConfig
-func FormatConfig(configContent *C.char, formattedOut **C.char) *C.char {
+func FormatConfig(configContent *C.char, formattedOut **C.char) *C.LibocError {
 	if configContent == nil {
-		return C.CString("configContent is null")
+		return newErrorMessage(C.LIBOC_ERROR_INVALID_ARGUMENT, "configContent is null")
 	}
 	if formattedOut == nil {
-		return C.CString("formattedOut is null")
-	}
-	ctx := liboc.BaseContext(nil)
-	options, err := parseConfig(ctx, C.GoString(configContent))
-	if err != nil {
-		return C.CString(err.Error())
+		return newErrorMessage(C.LIBOC_ERROR_INVALID_ARGUMENT, "formattedOut is null")
 	}
-	var buffer bytes.Buffer
-	encoder := json.NewEncoder(&buffer)
-	encoder.SetIndent("", "  ")
-	err = encoder.Encode(options)
+	formatted, err := liboc.FormatConfig(C.GoString(configContent))
 	if err != nil {
-		return C.CString(err.Error())
+		return newError(err, C.LIBOC_ERROR_CONFIG)
 	}
-	*formattedOut = C.CString(buffer.String())
+	*formattedOut = C.CString(formatted.Value)
 	return nil
 }
-//export LibocGetLastError
-func LibocGetLastError() *C.char {
-	lastErrorLock.Lock()
-	defer lastErrorLock.Unlock()
-	if lastError == "" {
-		return C.CString("")
-	}
-	return C.CString(lastError)
-}
-func parseConfig(ctx context.Context, configContent string) (option.Options, error) {
-	options, err := json.UnmarshalExtendedContext[option.Options](ctx, []byte(configContent))
-	if err != nil {
-		return option.Options{}, E.Cause(err, "parse config")
-	}
-	return options, nil
-}
 //export NewService
-func NewService(configContent *C.char, platformInterface *C.PlatformInterface) C.int64_t {
-	clearLastError()
+func NewService(configContent *C.char, platformInterface *C.PlatformInterface, serviceIDOut *C.int64_t) *C.LibocError {
 	if platformInterface == nil {
-		setLastError("PlatformInterface is null")
-		return -1
+		return newErrorMessage(C.LIBOC_ERROR_INVALID_ARGUMENT, "PlatformInterface is null")
 	}
 	if configContent == nil {
-		setLastError("configContent is null")
-		return -1
+		return newErrorMessage(C.LIBOC_ERROR_INVALID_ARGUMENT, "configContent is null")
+	}
+	if serviceIDOut == nil {
+		return newErrorMessage(C.LIBOC_ERROR_INVALID_ARGUMENT, "serviceIDOut is null")
 	}
 	config := C.GoString(configContent)
-	platformCopy := (*C.PlatformInterface)(C.malloc(C.size_t(unsafe.Sizeof(C.PlatformInterface{}))))
+	platformSize := C.size_t(platformInterface.size)
+	if platformSize < C.size_t(unsafe.Offsetof(platformInterface.usePlatformAutoDetectInterfaceControl)) {
+		return newErrorMessage(C.LIBOC_ERROR_INVALID_ARGUMENT, "unsupported PlatformInterface size")
+	}
+	if platformInterface.version == 0 || platformInterface.version > C.LIBOC_PLATFORM_INTERFACE_VERSION {
+		return newErrorMessage(C.LIBOC_ERROR_INVALID_ARGUMENT, "unsupported PlatformInterface version "+strconv.FormatUint(uint64(platformInterface.version), 10))
+	}
+	platformCopy := (*C.PlatformInterface)(C.calloc(1, C.size_t(unsafe.Sizeof(C.PlatformInterface{}))))
 	if platformCopy == nil {
-		setLastError("failed to allocate memory for platform interface")
-		return -1
+		return newErrorMessage(C.LIBOC_ERROR_UNKNOWN, "failed to allocate memory for platform interface")
 	}
-	*platformCopy = *platformInterface
-	goInterface := newWindowsPlatformInterface(platformCopy)
+	C.memcpy(unsafe.Pointer(platformCopy), unsafe.Pointer(platformInterface), min(platformSize, C.size_t(unsafe.Sizeof(C.PlatformInterface{}))))
+	goInterface := newFFIPlatformInterface(platformCopy)
 	service, err := liboc.NewService(config, goInterface)
 	if err != nil {
 		C.free(unsafe.Pointer(platformCopy))
-		setLastError(err.Error())
-		return -1
+		return newError(err, C.LIBOC_ERROR_CONFIG)
 	}
 	serviceMutex.Lock()
 	serviceID := nextServiceID
 	nextServiceID++
 	serviceRegistry.Store(serviceID, service)
-	platformRegistry.Store(serviceID, platformCopy)
+	platformRegistry.Store(serviceID, goInterface)
 	serviceMutex.Unlock()
-	return C.int64_t(serviceID)
+	*serviceIDOut = C.int64_t(serviceID)
+	return nil
 }
 //export ServiceStart
-func ServiceStart(serviceID C.int64_t) *C.char {
-	serviceInterface, ok := serviceRegistry.Load(int64(serviceID))
-	if !ok {
-		return C.CString("service not found")
-	}
-	service := serviceInterface.(*liboc.BoxService)
-	err := service.Start()
+func ServiceStart(serviceID C.int64_t) *C.LibocError {
+	service, err := loadServiceByID(int64(serviceID))
 	if err != nil {
-		return C.CString(err.Error())
+		return newError(err, C.LIBOC_ERROR_UNKNOWN)
 	}
-	return nil
+	return newError(service.Start(), C.LIBOC_ERROR_UNKNOWN)
 }
 //export ServiceClose
-func ServiceClose(serviceID C.int64_t) *C.char {
-	serviceInterface, ok := serviceRegistry.Load(int64(serviceID))
+func ServiceClose(serviceID C.int64_t) *C.LibocError {
+	return serviceClose(serviceID, 0)
+}
+//export ServiceCloseWithTimeout
+func ServiceCloseWithTimeout(serviceID C.int64_t, timeoutMs C.int32_t) *C.LibocError {
+	return serviceClose(serviceID, int32(timeoutMs))
+}
+func serviceClose(serviceID C.int64_t, timeoutMs int32) *C.LibocError {
+	return newError(closeService(int64(serviceID), timeoutMs), C.LIBOC_ERROR_UNKNOWN)
+}
+func closeService(serviceID int64, timeoutMs int32) error {
+	serviceInterface, ok := serviceRegistry.LoadAndDelete(serviceID)
 	if !ok {
-		return C.CString("service not found")
+		return newKindError(errorKindNotFound, E.New("service not found: ", serviceID))
 	}
+	closeServiceSubscriptions(serviceID)
 	service := serviceInterface.(*liboc.BoxService)
-	err := service.Close()
-	if platformInterface, ok := platformRegistry.Load(int64(serviceID)); ok {
-		if platform, ok := platformInterface.(*C.PlatformInterface); ok {
-			C.free(unsafe.Pointer(platform))
+	err := service.CloseWithTimeout(timeoutMs)
+	if platformInterface, ok := platformRegistry.LoadAndDelete(serviceID); ok {
+		platform := platformInterface.(*ffiPlatformInterface)
+		var timeoutErr *liboc.CloseTimeoutError
+		if errors.As(err, &timeoutErr) {
+			// The remaining components still call back into the table and
+			// use the tun devices until the background close finishes.
+			go func() {
+				timeoutErr.Wait()
+				platform.release()
+			}()
+		} else {
+			platform.release()
 		}
 	}
-	serviceRegistry.Delete(int64(serviceID))
-	platformRegistry.Delete(int64(serviceID))
-	cleanupAllTunDevices()
+	return err
+}
+type serviceInfo struct {
+	ID         int64           `json:"id"`
+	TunDevices []tunDeviceInfo `json:"tun_devices"`
+}
+type tunDeviceInfo struct {
+	Handle int32  `json:"handle"`
+	Name   string `json:"name"`
+}
+// ListServices returns a JSON array of the services that have not been closed
+// and the tun devices they created, to be freed with FreeString.
+//export ListServices
+func ListServices() *C.char {
+	content, _ := json.Marshal(listServices())
+	return C.CString(string(content))
+}
+func listServices() []serviceInfo {
+	services := []serviceInfo{}
+	platformRegistry.Range(func(key, value any) bool {
+		services = append(services, serviceInfo{
+			ID:         key.(int64),
+			TunDevices: value.(*ffiPlatformInterface).tunDeviceInfos(),
+		})
+		return true
+	})
+	slices.SortFunc(services, func(a, b serviceInfo) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return services
+}
+// LibocCall runs method with paramsJSON, which may be NULL for no
+// parameters, and sets resultOut to the JSON result, to be freed with
+// FreeString. LibocDescribe lists the methods.
+//export LibocCall
+func LibocCall(method *C.char, paramsJSON *C.char, resultOut **C.char) *C.LibocError {
+	if method == nil {
+		return newErrorMessage(C.LIBOC_ERROR_INVALID_ARGUMENT, "method is null")
+	}
+	if resultOut == nil {
+		return newErrorMessage(C.LIBOC_ERROR_INVALID_ARGUMENT, "resultOut is null")
+	}
+	var params []byte
+	if paramsJSON != nil {
+		params = []byte(C.GoString(paramsJSON))
+	}
+	result, err := callMethod(C.GoString(method), params)
 	if err != nil {
-		return C.CString(err.Error())
+		return newError(err, C.LIBOC_ERROR_UNKNOWN)
 	}
+	*resultOut = C.CString(string(result))
 	return nil
 }
-func cleanupAllTunDevices() {
-	tunDevicesMutex.Lock()
-	defer tunDevicesMutex.Unlock()
-	for fd, device := range tunDevices {
-		device.Close()
-		delete(tunDevices, fd)
+// LibocSubscribe delivers the JSON events of topic to callback until
+// LibocUnsubscribe is called or the service is closed.
+//export LibocSubscribe
+func LibocSubscribe(topic *C.char, paramsJSON *C.char, callback C.SubscribeFunc, userData unsafe.Pointer, subscriptionIDOut *C.int64_t) *C.LibocError {
+	if topic == nil {
+		return newErrorMessage(C.LIBOC_ERROR_INVALID_ARGUMENT, "topic is null")
+	}
+	if callback == nil {
+		return newErrorMessage(C.LIBOC_ERROR_INVALID_ARGUMENT, "callback is null")
+	}
+	if subscriptionIDOut == nil {
+		return newErrorMessage(C.LIBOC_ERROR_INVALID_ARGUMENT, "subscriptionIDOut is null")
 	}
+	var params []byte
+	if paramsJSON != nil {
+		params = []byte(C.GoString(paramsJSON))
+	}
+	subscriptionID, err := subscribe(C.GoString(topic), params, func(subscriptionID int64, event []byte) {
+		cEvent := C.CString(string(event))
+		defer C.free(unsafe.Pointer(cEvent))
+		C.call_subscribe(callback, C.int64_t(subscriptionID), cEvent, userData)
+	})
+	if err != nil {
+		return newError(err, C.LIBOC_ERROR_UNKNOWN)
+	}
+	*subscriptionIDOut = C.int64_t(subscriptionID)
+	return nil
+}
+//export LibocUnsubscribe
+func LibocUnsubscribe(subscriptionID C.int64_t) *C.LibocError {
+	return newError(unsubscribe(int64(subscriptionID)), C.LIBOC_ERROR_UNKNOWN)
+}
+// LibocDescribe returns a JSON description of the methods of LibocCall, the
+// topics of LibocSubscribe and the error codes, to be freed with FreeString.
+//export LibocDescribe
+func LibocDescribe() *C.char {
+	content, _ := json.Marshal(describe(map[string]int32{
+		"ok":               C.LIBOC_OK,
+		"unknown":          C.LIBOC_ERROR_UNKNOWN,
+		"invalid_argument": C.LIBOC_ERROR_INVALID_ARGUMENT,
+		"config":           C.LIBOC_ERROR_CONFIG,
+		"not_found":        C.LIBOC_ERROR_NOT_FOUND,
+		"timeout":          C.LIBOC_ERROR_TIMEOUT,
+		"permission":       C.LIBOC_ERROR_PERMISSION,
+		"platform":         C.LIBOC_ERROR_PLATFORM,
+	}))
+	return C.CString(string(content))
 }
 //export ServicePause
-func ServicePause(serviceID C.int64_t) {
-	serviceInterface, ok := serviceRegistry.Load(int64(serviceID))
-	if !ok {
-		return
+func ServicePause(serviceID C.int64_t) *C.LibocError {
+	service, err := loadServiceByID(int64(serviceID))
+	if err != nil {
+		return newError(err, C.LIBOC_ERROR_UNKNOWN)
 	}
-	service := serviceInterface.(*liboc.BoxService)
 	service.Pause()
+	return nil
 }
 //export ServiceWake
-func ServiceWake(serviceID C.int64_t) {
-	serviceInterface, ok := serviceRegistry.Load(int64(serviceID))
-	if !ok {
-		return
+func ServiceWake(serviceID C.int64_t) *C.LibocError {
+	service, err := loadServiceByID(int64(serviceID))
+	if err != nil {
+		return newError(err, C.LIBOC_ERROR_UNKNOWN)
 	}
-	service := serviceInterface.(*liboc.BoxService)
 	service.Wake()
+	return nil
 }
 //export ServiceNeedWIFIState
-func ServiceNeedWIFIState(serviceID C.int64_t) C.char {
-	serviceInterface, ok := serviceRegistry.Load(int64(serviceID))
-	if !ok {
-		return 0
+func ServiceNeedWIFIState(serviceID C.int64_t, needOut *C.int32_t) *C.LibocError {
+	if needOut == nil {
+		return newErrorMessage(C.LIBOC_ERROR_INVALID_ARGUMENT, "needOut is null")
 	}
-	service := serviceInterface.(*liboc.BoxService)
+	service, err := loadServiceByID(int64(serviceID))
+	if err != nil {
+		return newError(err, C.LIBOC_ERROR_UNKNOWN)
+	}
+	*needOut = 0
 	if service.NeedWIFIState() {
-		return 1
+		*needOut = 1
 	}
-	return 0
+	return nil
 }
-type windowsPlatformInterface struct {
+type ffiPlatformInterface struct {
 	cInterface *C.PlatformInterface
+	monitors   sync.Map
+	tunAccess  sync.Mutex
+	tunDevices []*trackedTun
 }
-func newWindowsPlatformInterface(cInterface *C.PlatformInterface) *windowsPlatformInterface {
-	return &windowsPlatformInterface{
+func newFFIPlatformInterface(cInterface *C.PlatformInterface) *ffiPlatformInterface {
+	return &ffiPlatformInterface{
 		cInterface: cInterface,
 	}
 }
-func (w *windowsPlatformInterface) LocalDNSTransport() liboc.LocalDNSTransport {
-	return nil
+func (w *ffiPlatformInterface) LocalDNSTransport() liboc.LocalDNSTransport {
+	if w.cInterface.lookup == nil {
+		return nil
+	}
+	return (*platformLocalDNSTransport)(w)
 }
-func (w *windowsPlatformInterface) UsePlatformAutoDetectInterfaceControl() bool {
-	return true
+func (w *ffiPlatformInterface) UsePlatformAutoDetectInterfaceControl() bool {
+	return C.call_bool(w.cInterface.usePlatformAutoDetectInterfaceControl, 1) != 0
 }
-func (w *windowsPlatformInterface) AutoDetectInterfaceControl(fd int32) error {
-	return nil
+func (w *ffiPlatformInterface) AutoDetectInterfaceControl(fd int32) error {
+	if w.cInterface.autoDetectInterfaceControl == nil {
+		return nil
+	}
+	return takeCError(C.call_autoDetectInterfaceControl(w.cInterface.autoDetectInterfaceControl, C.int32_t(fd)))
 }
-func (w *windowsPlatformInterface) OpenTun(options liboc.TunOptions) (int32, error) {
-	return 1, nil
+func (w *ffiPlatformInterface) UseNativeTun() bool {
+	return runtime.GOOS != "darwin" && w.cInterface.openTun == nil
 }
-var (
-	tunDevicesMutex sync.Mutex
-	tunDevices      = make(map[int32]tun.Tun)
-)
-func storeTunDevice(fd int32, device tun.Tun) {
-	tunDevicesMutex.Lock()
-	defer tunDevicesMutex.Unlock()
-	tunDevices[fd] = device
+func (w *ffiPlatformInterface) OpenTun(options liboc.TunOptions) (int32, error) {
+	if w.cInterface.openTun == nil {
+		return defaultTunnelFileDescriptor()
+	}
+	optionsJSON, err := json.Marshal(newTunOptionsJSON(options))
+	if err != nil {
+		return -1, err
+	}
+	cOptions := C.CString(string(optionsJSON))
+	defer C.free(unsafe.Pointer(cOptions))
+	var cError *C.char
+	tunFd := C.call_openTun(w.cInterface.openTun, cOptions, &cError)
+	err = takeCError(cError)
+	if err != nil {
+		return -1, err
+	}
+	if tunFd < 0 {
+		return -1, &callbackError{"OpenTun callback failed"}
+	}
+	return int32(tunFd), nil
+}
+type tunOptionsJSON struct {
+	Inet4Address             []string `json:"inet4_address,omitempty"`
+	Inet6Address             []string `json:"inet6_address,omitempty"`
+	DNSServerAddress         string   `json:"dns_server_address,omitempty"`
+	MTU                      int32    `json:"mtu"`
+	AutoRoute                bool     `json:"auto_route"`
+	StrictRoute              bool     `json:"strict_route"`
+	Inet4RouteAddress        []string `json:"inet4_route_address,omitempty"`
+	Inet6RouteAddress        []string `json:"inet6_route_address,omitempty"`
+	Inet4RouteExcludeAddress []string `json:"inet4_route_exclude_address,omitempty"`
+	Inet6RouteExcludeAddress []string `json:"inet6_route_exclude_address,omitempty"`
+	Inet4RouteRange          []string `json:"inet4_route_range,omitempty"`
+	Inet6RouteRange          []string `json:"inet6_route_range,omitempty"`
+	HTTPProxyEnabled         bool     `json:"http_proxy_enabled"`
+	HTTPProxyServer          string   `json:"http_proxy_server,omitempty"`
+	HTTPProxyServerPort      int32    `json:"http_proxy_server_port,omitempty"`
+	HTTPProxyBypassDomain    []string `json:"http_proxy_bypass_domain,omitempty"`
+	HTTPProxyMatchDomain     []string `json:"http_proxy_match_domain,omitempty"`
+	IncludePackage           []string `json:"include_package,omitempty"`
+	ExcludePackage           []string `json:"exclude_package,omitempty"`
+}
+func newTunOptionsJSON(options liboc.TunOptions) *tunOptionsJSON {
+	var dnsServerAddress string
+	if address, err := options.GetDNSServerAddress(); err == nil {
+		dnsServerAddress = address.Value
+	}
+	return &tunOptionsJSON{
+		Inet4Address:             routePrefixStrings(options.GetInet4Address()),
+		Inet6Address:             routePrefixStrings(options.GetInet6Address()),
+		DNSServerAddress:         dnsServerAddress,
+		MTU:                      options.GetMTU(),
+		AutoRoute:                options.GetAutoRoute(),
+		StrictRoute:              options.GetStrictRoute(),
+		Inet4RouteAddress:        routePrefixStrings(options.GetInet4RouteAddress()),
+		Inet6RouteAddress:        routePrefixStrings(options.GetInet6RouteAddress()),
+		Inet4RouteExcludeAddress: routePrefixStrings(options.GetInet4RouteExcludeAddress()),
+		Inet6RouteExcludeAddress: routePrefixStrings(options.GetInet6RouteExcludeAddress()),
+		Inet4RouteRange:          routePrefixStrings(options.GetInet4RouteRange()),
+		Inet6RouteRange:          routePrefixStrings(options.GetInet6RouteRange()),
+		HTTPProxyEnabled:         options.IsHTTPProxyEnabled(),
+		HTTPProxyServer:          options.GetHTTPProxyServer(),
+		HTTPProxyServerPort:      options.GetHTTPProxyServerPort(),
+		HTTPProxyBypassDomain:    iteratorStrings(options.GetHTTPProxyBypassDomain()),
+		HTTPProxyMatchDomain:     iteratorStrings(options.GetHTTPProxyMatchDomain()),
+		IncludePackage:           iteratorStrings(options.GetIncludePackage()),
+		ExcludePackage:           iteratorStrings(options.GetExcludePackage()),
+	}
 }
-func GetTunDevice(fd int32) tun.Tun {
-	tunDevicesMutex.Lock()
-	defer tunDevicesMutex.Unlock()
-	return tunDevices[fd]
+func routePrefixStrings(iterator liboc.RoutePrefixIterator) []string {
+	var prefixes []string
+	for iterator.HasNext() {
+		prefixes = append(prefixes, iterator.Next().String())
+	}
+	return prefixes
 }
-func removeTunDevice(fd int32) {
-	tunDevicesMutex.Lock()
-	defer tunDevicesMutex.Unlock()
-	if device, ok := tunDevices[fd]; ok {
-		device.Close()
-		delete(tunDevices, fd)
+func iteratorStrings(iterator liboc.StringIterator) []string {
+	var values []string
+	for iterator.HasNext() {
+		values = append(values, iterator.Next())
 	}
+	return values
 }
-func (w *windowsPlatformInterface) WriteLog(message string) {
+// takeCError converts an error string returned by a callback and frees it.
+func takeCError(cError *C.char) error {
+	if cError == nil {
+		return nil
+	}
+	defer C.free(unsafe.Pointer(cError))
+	return &callbackError{C.GoString(cError)}
+}
+func takeCString(cString *C.char) string {
+	if cString == nil {
+		return ""
+	}
+	defer C.free(unsafe.Pointer(cString))
+	return C.GoString(cString)
+}
+var nextTunHandle atomic.Int32
+type trackedTun struct {
+	handle int32
+	name   string
+	device tun.Tun
+}
+// TrackTun records a tun device created by liboc for this service, so that it
+// is closed with the service even if the instance owning it leaked.
+func (w *ffiPlatformInterface) TrackTun(device tun.Tun) int32 {
+	name, _ := device.Name()
+	tracked := &trackedTun{handle: nextTunHandle.Add(1), name: name, device: device}
+	w.tunAccess.Lock()
+	defer w.tunAccess.Unlock()
+	w.tunDevices = append(w.tunDevices, tracked)
+	return tracked.handle
+}
+func (w *ffiPlatformInterface) UntrackTun(handle int32) {
+	w.tunAccess.Lock()
+	defer w.tunAccess.Unlock()
+	w.tunDevices = slices.DeleteFunc(w.tunDevices, func(tracked *trackedTun) bool {
+		return tracked.handle == handle
+	})
+}
+func (w *ffiPlatformInterface) tunDeviceInfos() []tunDeviceInfo {
+	w.tunAccess.Lock()
+	defer w.tunAccess.Unlock()
+	infos := make([]tunDeviceInfo, 0, len(w.tunDevices))
+	for _, tracked := range w.tunDevices {
+		infos = append(infos, tunDeviceInfo{Handle: tracked.handle, Name: tracked.name})
+	}
+	return infos
+}
+func (w *ffiPlatformInterface) closeTunDevices() {
+	w.tunAccess.Lock()
+	defer w.tunAccess.Unlock()
+	for _, tracked := range w.tunDevices {
+		tracked.device.Close()
+	}
+	w.tunDevices = nil
+}
+// release closes the remaining tun devices and frees the callback table once
+// the service no longer uses them.
+func (w *ffiPlatformInterface) release() {
+	w.closeTunDevices()
+	C.free(unsafe.Pointer(w.cInterface))
+}
+func (w *ffiPlatformInterface) WriteLog(message string) {
 	if w.cInterface != nil && w.cInterface.writeLog != nil {
 		cMessage := C.CString(message)
 		C.call_writeLog(w.cInterface.writeLog, cMessage)
 		C.free(unsafe.Pointer(cMessage))
 	}
 }
-func (w *windowsPlatformInterface) DisableColors() bool {
+func (w *ffiPlatformInterface) DisableColors() bool {
 	return true
 }
-func (w *windowsPlatformInterface) UseProcFS() bool {
+func (w *ffiPlatformInterface) UseProcFS() bool {
 	return false
 }
-func (w *windowsPlatformInterface) FindConnectionOwner(ipProtocol int32, sourceAddress string, sourcePort int32, destinationAddress string, destinationPort int32) (int32, error) {
+func (w *ffiPlatformInterface) FindConnectionOwner(ipProtocol int32, sourceAddress string, sourcePort int32, destinationAddress string, destinationPort int32) (int32, error) {
 	if w.cInterface == nil || w.cInterface.findConnectionOwner == nil {
 		return -1, E.New("FindConnectionOwner callback not set")
 	}
@@ -385,7 +817,7 @@ func (w *windowsPlatformInterface) FindConnectionOwner(ipProtocol int32, sourceA
 	)
 	return int32(result), nil
 }
-func (w *windowsPlatformInterface) PackageNameByUid(uid int32) (string, error) {
+func (w *ffiPlatformInterface) PackageNameByUid(uid int32) (string, error) {
 	if w.cInterface == nil || w.cInterface.packageNameByUid == nil {
 		return "", E.New("PackageNameByUid callback not set")
 	}
@@ -396,7 +828,7 @@ func (w *windowsPlatformInterface) PackageNameByUid(uid int32) (string, error) {
 	defer C.free(unsafe.Pointer(cResult))
 	return C.GoString(cResult), nil
 }
-func (w *windowsPlatformInterface) UidByPackageName(packageName string) (int32, error) {
+func (w *ffiPlatformInterface) UidByPackageName(packageName string) (int32, error) {
 	if w.cInterface == nil || w.cInterface.uidByPackageName == nil {
 		return -1, E.New("UidByPackageName callback not set")
 	}
@@ -405,37 +837,217 @@ func (w *windowsPlatformInterface) UidByPackageName(packageName string) (int32,
 	result := C.call_uidByPackageName(w.cInterface.uidByPackageName, cPackageName)
 	return int32(result), nil
 }
-func (w *windowsPlatformInterface) StartDefaultInterfaceMonitor(listener liboc.InterfaceUpdateListener) error {
+var (
+	monitorRegistry sync.Map
+	nextMonitorID   atomic.Int64
+)
+//export libocInterfaceUpdate
+func libocInterfaceUpdate(monitorID C.int64_t, interfaceName *C.char, interfaceIndex C.int32_t, isExpensive C.int32_t, isConstrained C.int32_t) {
+	listener, loaded := monitorRegistry.Load(int64(monitorID))
+	if !loaded {
+		return
+	}
+	listener.(*interfaceMonitor).listener.UpdateDefaultInterface(C.GoString(interfaceName), int32(interfaceIndex), isExpensive != 0, isConstrained != 0)
+}
+type interfaceMonitor struct {
+	id             int64
+	listener       liboc.InterfaceUpdateListener
+	networkMonitor tun.NetworkUpdateMonitor
+	defaultMonitor tun.DefaultInterfaceMonitor
+}
+func (w *ffiPlatformInterface) StartDefaultInterfaceMonitor(listener liboc.InterfaceUpdateListener) error {
+	if w.cInterface.startDefaultInterfaceMonitor == nil {
+		return w.startNativeInterfaceMonitor(listener)
+	}
+	monitor := &interfaceMonitor{id: nextMonitorID.Add(1), listener: listener}
+	monitorRegistry.Store(monitor.id, monitor)
+	w.monitors.Store(listener, monitor)
+	err := takeCError(C.call_startDefaultInterfaceMonitor(w.cInterface.startDefaultInterfaceMonitor, C.int64_t(monitor.id)))
+	if err != nil {
+		monitorRegistry.Delete(monitor.id)
+		w.monitors.Delete(listener)
+		return err
+	}
 	return nil
 }
-func (w *windowsPlatformInterface) CloseDefaultInterfaceMonitor(listener liboc.InterfaceUpdateListener) error {
+func (w *ffiPlatformInterface) CloseDefaultInterfaceMonitor(listener liboc.InterfaceUpdateListener) error {
+	rawMonitor, loaded := w.monitors.LoadAndDelete(listener)
+	if !loaded {
+		return nil
+	}
+	monitor := rawMonitor.(*interfaceMonitor)
+	if monitor.defaultMonitor != nil {
+		return common.Close(monitor.defaultMonitor, monitor.networkMonitor)
+	}
+	monitorRegistry.Delete(monitor.id)
+	if w.cInterface.closeDefaultInterfaceMonitor == nil {
+		return nil
+	}
+	return takeCError(C.call_closeDefaultInterfaceMonitor(w.cInterface.closeDefaultInterfaceMonitor, C.int64_t(monitor.id)))
+}
+// startNativeInterfaceMonitor follows the default route with sing-tun for
+// hosts that do not report the default interface themselves.
+func (w *ffiPlatformInterface) startNativeInterfaceMonitor(listener liboc.InterfaceUpdateListener) error {
+	networkMonitor, err := tun.NewNetworkUpdateMonitor(logger.NOP())
+	if err != nil {
+		return E.Cause(err, "create network monitor")
+	}
+	defaultMonitor, err := tun.NewDefaultInterfaceMonitor(networkMonitor, logger.NOP(), tun.DefaultInterfaceMonitorOptions{
+		InterfaceFinder: control.NewDefaultInterfaceFinder(),
+	})
+	if err != nil {
+		return E.Cause(err, "create default interface monitor")
+	}
+	defaultMonitor.RegisterCallback(func(defaultInterface *control.Interface, flags int) {
+		if defaultInterface == nil {
+			listener.UpdateDefaultInterface("", -1, false, false)
+			return
+		}
+		listener.UpdateDefaultInterface(defaultInterface.Name, int32(defaultInterface.Index), false, false)
+	})
+	err = networkMonitor.Start()
+	if err != nil {
+		return E.Cause(err, "start network monitor")
+	}
+	err = defaultMonitor.Start()
+	if err != nil {
+		networkMonitor.Close()
+		return E.Cause(err, "start default interface monitor")
+	}
+	w.monitors.Store(listener, &interfaceMonitor{
+		listener:       listener,
+		networkMonitor: networkMonitor,
+		defaultMonitor: defaultMonitor,
+	})
 	return nil
 }
-func (w *windowsPlatformInterface) GetInterfaces() (liboc.NetworkInterfaceIterator, error) {
-	interfaces, err := getWindowsNetworkInterfaces()
+type networkInterfaceJSON struct {
+	Index      int32    `json:"index"`
+	MTU        int32    `json:"mtu"`
+	Name       string   `json:"name"`
+	Addresses  []string `json:"addresses"`
+	Flags      int32    `json:"flags"`
+	Type       int32    `json:"type"`
+	DNSServers []string `json:"dns_servers"`
+	Metered    bool     `json:"metered"`
+}
+func (w *ffiPlatformInterface) GetInterfaces() (liboc.NetworkInterfaceIterator, error) {
+	if w.cInterface.getInterfaces == nil {
+		interfaces, err := getSystemNetworkInterfaces()
+		if err != nil {
+			return nil, err
+		}
+		return &networkInterfaceIterator{items: interfaces}, nil
+	}
+	var cError *C.char
+	content := takeCString(C.call_getInterfaces(w.cInterface.getInterfaces, &cError))
+	err := takeCError(cError)
 	if err != nil {
 		return nil, err
 	}
-	return &windowsNetworkInterfaceIterator{items: interfaces}, nil
+	var interfacesJSON []networkInterfaceJSON
+	err = json.Unmarshal([]byte(content), &interfacesJSON)
+	if err != nil {
+		return nil, E.Cause(err, "decode interfaces")
+	}
+	interfaces := make([]*liboc.NetworkInterface, 0, len(interfacesJSON))
+	for _, networkInterface := range interfacesJSON {
+		interfaces = append(interfaces, &liboc.NetworkInterface{
+			Index:     networkInterface.Index,
+			MTU:       networkInterface.MTU,
+			Name:      networkInterface.Name,
+			Addresses: &stringIterator{items: networkInterface.Addresses},
+			Flags:     networkInterface.Flags,
+			Type:      networkInterface.Type,
+			DNSServer: &stringIterator{items: networkInterface.DNSServers},
+			Metered:   networkInterface.Metered,
+		})
+	}
+	return &networkInterfaceIterator{items: interfaces}, nil
 }
-func (w *windowsPlatformInterface) UnderNetworkExtension() bool {
-	return false
+func (w *ffiPlatformInterface) UnderNetworkExtension() bool {
+	return C.call_bool(w.cInterface.underNetworkExtension, 0) != 0
 }
-func (w *windowsPlatformInterface) IncludeAllNetworks() bool {
-	return false
+func (w *ffiPlatformInterface) IncludeAllNetworks() bool {
+	return C.call_bool(w.cInterface.includeAllNetworks, 0) != 0
 }
-func (w *windowsPlatformInterface) ReadWIFIState() *liboc.WIFIState {
-	return nil
+func (w *ffiPlatformInterface) ReadWIFIState() *liboc.WIFIState {
+	if w.cInterface.readWIFIState == nil {
+		return nil
+	}
+	var cSSID, cBSSID *C.char
+	result := C.call_readWIFIState(w.cInterface.readWIFIState, &cSSID, &cBSSID)
+	ssid, bssid := takeCString(cSSID), takeCString(cBSSID)
+	if result == 0 {
+		return nil
+	}
+	return liboc.NewWIFIState(ssid, bssid)
+}
+func (w *ffiPlatformInterface) SystemCertificates() liboc.StringIterator {
+	if w.cInterface.systemCertificates == nil {
+		return &emptyStringIterator{}
+	}
+	content := takeCString(C.call_systemCertificates(w.cInterface.systemCertificates))
+	var certificates []string
+	if content != "" && json.Unmarshal([]byte(content), &certificates) != nil {
+		certificates = nil
+	}
+	return &stringIterator{items: certificates}
+}
+func (w *ffiPlatformInterface) ClearDNSCache() {
+	if w.cInterface.clearDNSCache != nil {
+		C.call_clearDNSCache(w.cInterface.clearDNSCache)
+	}
 }
-func (w *windowsPlatformInterface) SystemCertificates() liboc.StringIterator {
-	return &emptyStringIterator{}
+func (w *ffiPlatformInterface) SendNotification(notification *liboc.Notification) error {
+	if w.cInterface.sendNotification == nil {
+		return nil
+	}
+	cNotification := C.Notification{
+		identifier: C.CString(notification.Identifier),
+		typeName:   C.CString(notification.TypeName),
+		typeID:     C.int32_t(notification.TypeID),
+		title:      C.CString(notification.Title),
+		subtitle:   C.CString(notification.Subtitle),
+		body:       C.CString(notification.Body),
+		openURL:    C.CString(notification.OpenURL),
+	}
+	defer func() {
+		for _, cString := range []*C.char{cNotification.identifier, cNotification.typeName, cNotification.title, cNotification.subtitle, cNotification.body, cNotification.openURL} {
+			C.free(unsafe.Pointer(cString))
+		}
+	}()
+	return takeCError(C.call_sendNotification(w.cInterface.sendNotification, &cNotification))
 }
-func (w *windowsPlatformInterface) ClearDNSCache() {
+type platformLocalDNSTransport ffiPlatformInterface
+func (t *platformLocalDNSTransport) Raw() bool {
+	return false
 }
-func (w *windowsPlatformInterface) SendNotification(notification *liboc.Notification) error {
+func (t *platformLocalDNSTransport) Lookup(ctx *liboc.ExchangeContext, network string, domain string) error {
+	cNetwork := C.CString(network)
+	defer C.free(unsafe.Pointer(cNetwork))
+	cDomain := C.CString(domain)
+	defer C.free(unsafe.Pointer(cDomain))
+	var (
+		cAddresses *C.char
+		rcode      C.int32_t
+	)
+	err := takeCError(C.call_lookup(t.cInterface.lookup, cNetwork, cDomain, &cAddresses, &rcode))
+	addresses := takeCString(cAddresses)
+	if err != nil {
+		return err
+	}
+	if rcode != 0 {
+		ctx.ErrorCode(int32(rcode))
+		return nil
+	}
+	ctx.Success(&stringIterator{items: strings.Fields(addresses)})
 	return nil
 }
-func getWindowsNetworkInterfaces() ([]*liboc.NetworkInterface, error) {
+func (t *platformLocalDNSTransport) Exchange(ctx *liboc.ExchangeContext, message []byte) error {
+	return os.ErrInvalid
+}
+func getSystemNetworkInterfaces() ([]*liboc.NetworkInterface, error) {
 	goInterfaces, err := net.Interfaces()
 	if err != nil {
 		return nil, err
@@ -492,11 +1104,11 @@ func (e *stringIterator) HasNext() bool {
 func (e *stringIterator) Len() int32 {
 	return int32(len(e.items))
 }
-type windowsNetworkInterfaceIterator struct {
+type networkInterfaceIterator struct {
 	items []*liboc.NetworkInterface
 	index int
 }
-func (e *windowsNetworkInterfaceIterator) Next() *liboc.NetworkInterface {
+func (e *networkInterfaceIterator) Next() *liboc.NetworkInterface {
 	if e.index >= len(e.items) {
 		return nil
 	}
@@ -504,7 +1116,7 @@ func (e *windowsNetworkInterfaceIterator) Next() *liboc.NetworkInterface {
 	e.index++
 	return item
 }
-func (e *windowsNetworkInterfaceIterator) HasNext() bool {
+func (e *networkInterfaceIterator) HasNext() bool {
 	return e.index < len(e.items)
 }
 type emptyStringIterator struct {
@@ -0,0 +1,99 @@
+// harness loads the liboc shared library at runtime and checks that the
+// basic exports work:
+//
+//     export/build.sh build
+//     cc -o build/harness export/harness/harness.c -ldl
+//     build/harness build/liboc.so
+//
+// It exits with a non-zero status if any check fails.
+#include <stdint.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#ifdef _WIN32
+#include <windows.h>
+typedef HMODULE library_t;
+#define library_open(path) LoadLibraryA(path)
+#define library_symbol(library, name) ((void*)GetProcAddress(library, name))
+#else
+#include <dlfcn.h>
+typedef void* library_t;
+#define library_open(path) dlopen(path, RTLD_NOW | RTLD_LOCAL)
+#define library_symbol(library, name) dlsym(library, name)
+#endif
+
+#define LIBOC_ERROR_CONFIG 3
+
+typedef struct LibocError LibocError;
+typedef char* (*VersionFunc)(void);
+typedef LibocError* (*CheckConfigFunc)(char* configContent);
+typedef char* (*ListServicesFunc)(void);
+typedef void (*FreeStringFunc)(char* str);
+typedef int32_t (*LibocErrorCodeFunc)(LibocError* err);
+typedef const char* (*LibocErrorMessageFunc)(LibocError* err);
+typedef void (*LibocErrorFreeFunc)(LibocError* err);
+
+static const char* validConfig = "{\"log\":{\"level\":\"info\"},\"outbounds\":[{\"type\":\"direct\",\"tag\":\"direct\"}]}";
+static const char* invalidConfig = "{\"outbounds\":[{\"type\":\"no-such-type\",\"tag\":\"direct\"}]}";
+
+int main(int argc, char** argv) {
+    if (argc < 2) {
+        fprintf(stderr, "usage: %s <path to liboc shared library>\n", argv[0]);
+        return 2;
+    }
+    library_t library = library_open(argv[1]);
+    if (library == NULL) {
+        fprintf(stderr, "load %s failed\n", argv[1]);
+        return 1;
+    }
+    VersionFunc version = (VersionFunc)library_symbol(library, "Version");
+    CheckConfigFunc checkConfig = (CheckConfigFunc)library_symbol(library, "CheckConfig");
+    ListServicesFunc listServices = (ListServicesFunc)library_symbol(library, "ListServices");
+    FreeStringFunc freeString = (FreeStringFunc)library_symbol(library, "FreeString");
+    LibocErrorCodeFunc errorCode = (LibocErrorCodeFunc)library_symbol(library, "LibocErrorCode");
+    LibocErrorMessageFunc errorMessage = (LibocErrorMessageFunc)library_symbol(library, "LibocErrorMessage");
+    LibocErrorFreeFunc errorFree = (LibocErrorFreeFunc)library_symbol(library, "LibocErrorFree");
+    if (version == NULL || checkConfig == NULL || listServices == NULL || freeString == NULL || errorCode == NULL || errorMessage == NULL || errorFree == NULL) {
+        fprintf(stderr, "missing exports\n");
+        return 1;
+    }
+    int failed = 0;
+
+    char* versionString = version();
+    printf("Version: %s\n", versionString);
+    if (versionString == NULL || versionString[0] == '\0') {
+        failed = 1;
+    }
+    freeString(versionString);
+
+    LibocError* checkError = checkConfig((char*)validConfig);
+    if (checkError != NULL) {
+        printf("CheckConfig(valid): unexpected error: %s\n", errorMessage(checkError));
+        failed = 1;
+    } else {
+        printf("CheckConfig(valid): ok\n");
+    }
+    errorFree(checkError);
+
+    checkError = checkConfig((char*)invalidConfig);
+    if (checkError == NULL) {
+        printf("CheckConfig(invalid): unexpected success\n");
+        failed = 1;
+    } else {
+        printf("CheckConfig(invalid): [%d] %s\n", errorCode(checkError), errorMessage(checkError));
+        if (errorCode(checkError) != LIBOC_ERROR_CONFIG) {
+            failed = 1;
+        }
+    }
+    errorFree(checkError);
+
+    char* services = listServices();
+    printf("ListServices: %s\n", services);
+    if (services == NULL || strcmp(services, "[]") != 0) {
+        failed = 1;
+    }
+    freeString(services);
+
+    // The Go runtime cannot be unloaded, so the library stays open.
+    return failed;
+}
@@ -0,0 +1,766 @@
+//go:build (windows || linux || darwin) && !android && !ios && cgo
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	liboc "github.com/Open-Application/OpenCore"
+	"github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+// LibocCall and LibocSubscribe dispatch to the methods and topics below, so
+// that language bindings only need to wrap two entry points. Parameters,
+// results and events are JSON objects described by LibocDescribe.
+
+type errorKind int
+
+const (
+	errorKindInvalidArgument errorKind = iota + 1
+	errorKindNotFound
+	errorKindConfig
+)
+
+// kindError marks err with the category used to pick its error code.
+type kindError struct {
+	kind errorKind
+	err  error
+}
+
+func (e *kindError) Error() string {
+	return e.err.Error()
+}
+
+func (e *kindError) Unwrap() error {
+	return e.err
+}
+
+func newKindError(kind errorKind, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &kindError{kind, err}
+}
+
+func loadServiceByID(serviceID int64) (*liboc.BoxService, error) {
+	serviceInterface, loaded := serviceRegistry.Load(serviceID)
+	if !loaded {
+		return nil, newKindError(errorKindNotFound, E.New("service not found: ", serviceID))
+	}
+	return serviceInterface.(*liboc.BoxService), nil
+}
+
+type rpcMethod struct {
+	name        string
+	description string
+	paramsType  reflect.Type
+	resultType  reflect.Type
+	call        func(params []byte) (any, error)
+}
+
+func newMethod[P any, R any](name string, description string, call func(params *P) (*R, error)) *rpcMethod {
+	return &rpcMethod{
+		name:        name,
+		description: description,
+		paramsType:  reflect.TypeFor[P](),
+		resultType:  reflect.TypeFor[R](),
+		call: func(content []byte) (any, error) {
+			var params P
+			err := decodeParams(content, &params)
+			if err != nil {
+				return nil, err
+			}
+			return call(&params)
+		},
+	}
+}
+
+type rpcTopic struct {
+	name        string
+	description string
+	paramsType  reflect.Type
+	eventType   reflect.Type
+	subscribe   func(params []byte, emit func(event any)) (serviceID int64, cancel func(), err error)
+}
+
+func newTopic[P any, V any](name string, description string, subscribe func(params *P, emit func(event *V)) (int64, func(), error)) *rpcTopic {
+	return &rpcTopic{
+		name:        name,
+		description: description,
+		paramsType:  reflect.TypeFor[P](),
+		eventType:   reflect.TypeFor[V](),
+		subscribe: func(content []byte, emit func(event any)) (int64, func(), error) {
+			var params P
+			err := decodeParams(content, &params)
+			if err != nil {
+				return 0, nil, err
+			}
+			return subscribe(&params, func(event *V) {
+				emit(event)
+			})
+		},
+	}
+}
+
+func decodeParams(content []byte, params any) error {
+	content = bytes.TrimSpace(content)
+	if len(content) == 0 || bytes.Equal(content, []byte("null")) {
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(params)
+	if err != nil {
+		return newKindError(errorKindInvalidArgument, E.Cause(err, "decode params"))
+	}
+	return nil
+}
+
+type emptyParams struct{}
+
+type emptyResult struct{}
+
+type configParams struct {
+	Config string `json:"config"`
+}
+
+type localeParams struct {
+	Locale string `json:"locale"`
+}
+
+type memoryLimitParams struct {
+	Enabled bool `json:"enabled"`
+}
+
+type serviceParams struct {
+	ServiceID int64 `json:"service_id"`
+}
+
+func (p serviceParams) serviceID() int64 {
+	return p.ServiceID
+}
+
+type serviceCloseParams struct {
+	serviceParams
+	TimeoutMs int32 `json:"timeout_ms,omitempty"`
+}
+
+type serviceConfigParams struct {
+	serviceParams
+	Config string `json:"config"`
+}
+
+type closeConnectionParams struct {
+	serviceParams
+	ConnectionID string `json:"connection_id"`
+}
+
+type selectOutboundParams struct {
+	serviceParams
+	Group    string `json:"group"`
+	Outbound string `json:"outbound"`
+}
+
+type urlTestParams struct {
+	serviceParams
+	Group string `json:"group"`
+}
+
+type clashModeParams struct {
+	serviceParams
+	Mode string `json:"mode"`
+}
+
+type logLevelParams struct {
+	serviceParams
+	Level string `json:"level"`
+}
+
+type intervalParams struct {
+	serviceParams
+	IntervalMs int32 `json:"interval_ms,omitempty"`
+}
+
+type versionResult struct {
+	Version string `json:"version"`
+}
+
+type formatConfigResult struct {
+	Config string `json:"config"`
+}
+
+type configDiagnosticJSON struct {
+	Severity string `json:"severity"`
+	Pointer  string `json:"pointer"`
+	Line     int32  `json:"line"`
+	Column   int32  `json:"column"`
+	Message  string `json:"message"`
+}
+
+type validateConfigResult struct {
+	Diagnostics []configDiagnosticJSON `json:"diagnostics"`
+}
+
+type deprecatedNoteJSON struct {
+	Name          string `json:"name"`
+	Message       string `json:"message"`
+	Impending     bool   `json:"impending"`
+	MigrationLink string `json:"migration_link,omitempty"`
+}
+
+func newDeprecatedNotesJSON(iterator liboc.DeprecatedNoteIterator) []deprecatedNoteJSON {
+	notes := []deprecatedNoteJSON{}
+	for iterator.HasNext() {
+		note := iterator.Next()
+		notes = append(notes, deprecatedNoteJSON{
+			Name:          note.Name,
+			Message:       note.Message(),
+			Impending:     note.Impending(),
+			MigrationLink: note.MigrationLink,
+		})
+	}
+	return notes
+}
+
+type migrateConfigResult struct {
+	Config string               `json:"config"`
+	Notes  []deprecatedNoteJSON `json:"notes"`
+}
+
+type deprecatedNotesResult struct {
+	Notes []deprecatedNoteJSON `json:"notes"`
+}
+
+type listServicesResult struct {
+	Services []serviceInfo `json:"services"`
+}
+
+type needWIFIStateResult struct {
+	NeedWIFIState bool `json:"need_wifi_state"`
+}
+
+type outboundTrafficJSON struct {
+	Tag           string `json:"tag"`
+	Uplink        int64  `json:"uplink"`
+	Downlink      int64  `json:"downlink"`
+	UplinkTotal   int64  `json:"uplink_total"`
+	DownlinkTotal int64  `json:"downlink_total"`
+}
+
+type trafficJSON struct {
+	Timestamp     int64                 `json:"timestamp"`
+	Uplink        int64                 `json:"uplink"`
+	Downlink      int64                 `json:"downlink"`
+	UplinkTotal   int64                 `json:"uplink_total"`
+	DownlinkTotal int64                 `json:"downlink_total"`
+	Outbounds     []outboundTrafficJSON `json:"outbounds"`
+}
+
+func newTrafficJSON(snapshot *liboc.TrafficSnapshot) *trafficJSON {
+	traffic := &trafficJSON{
+		Timestamp:     snapshot.Timestamp,
+		Uplink:        snapshot.Uplink,
+		Downlink:      snapshot.Downlink,
+		UplinkTotal:   snapshot.UplinkTotal,
+		DownlinkTotal: snapshot.DownlinkTotal,
+		Outbounds:     []outboundTrafficJSON{},
+	}
+	outbounds := snapshot.Outbounds()
+	for outbounds.HasNext() {
+		outbound := outbounds.Next()
+		traffic.Outbounds = append(traffic.Outbounds, outboundTrafficJSON(*outbound))
+	}
+	return traffic
+}
+
+type connectionJSON struct {
+	ID            string   `json:"id"`
+	Inbound       string   `json:"inbound"`
+	InboundType   string   `json:"inbound_type"`
+	IPVersion     int32    `json:"ip_version"`
+	Network       string   `json:"network"`
+	Source        string   `json:"source"`
+	Destination   string   `json:"destination"`
+	Domain        string   `json:"domain"`
+	Protocol      string   `json:"protocol"`
+	User          string   `json:"user"`
+	FromOutbound  string   `json:"from_outbound"`
+	CreatedAt     int64    `json:"created_at"`
+	ClosedAt      int64    `json:"closed_at"`
+	Uplink        int64    `json:"uplink"`
+	Downlink      int64    `json:"downlink"`
+	UplinkTotal   int64    `json:"uplink_total"`
+	DownlinkTotal int64    `json:"downlink_total"`
+	Rule          string   `json:"rule"`
+	Outbound      string   `json:"outbound"`
+	OutboundType  string   `json:"outbound_type"`
+	ChainList     []string `json:"chain"`
+	ProcessID     int32    `json:"process_id"`
+	ProcessPath   string   `json:"process_path"`
+	PackageName   string   `json:"package_name"`
+	UserID        int32    `json:"user_id"`
+}
+
+type connectionsResult struct {
+	Connections []connectionJSON `json:"connections"`
+}
+
+func newConnectionsResult(iterator liboc.ConnectionIterator) *connectionsResult {
+	result := &connectionsResult{Connections: []connectionJSON{}}
+	for iterator.HasNext() {
+		connection := iterator.Next()
+		item := connectionJSON(*connection)
+		if item.ChainList == nil {
+			item.ChainList = []string{}
+		}
+		result.Connections = append(result.Connections, item)
+	}
+	return result
+}
+
+type outboundGroupItemJSON struct {
+	Tag          string `json:"tag"`
+	Type         string `json:"type"`
+	URLTestTime  int64  `json:"url_test_time"`
+	URLTestDelay int32  `json:"url_test_delay"`
+}
+
+type outboundGroupJSON struct {
+	Tag        string                  `json:"tag"`
+	Type       string                  `json:"type"`
+	Selectable bool                    `json:"selectable"`
+	Selected   string                  `json:"selected"`
+	Items      []outboundGroupItemJSON `json:"items"`
+}
+
+type outboundGroupsResult struct {
+	Groups []outboundGroupJSON `json:"groups"`
+}
+
+type clashModeResult struct {
+	Mode  string   `json:"mode"`
+	Modes []string `json:"modes"`
+}
+
+type logEntryJSON struct {
+	Level     string `json:"level"`
+	Message   string `json:"message"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+type logsResult struct {
+	Logs []logEntryJSON `json:"logs"`
+}
+
+func newLogsResult(iterator liboc.LogEntryIterator) *logsResult {
+	result := &logsResult{Logs: []logEntryJSON{}}
+	for iterator.HasNext() {
+		entry := iterator.Next()
+		result.Logs = append(result.Logs, logEntryJSON{
+			Level:     log.FormatLevel(log.Level(entry.Level)),
+			Message:   entry.Message,
+			Timestamp: entry.Timestamp,
+		})
+	}
+	return result
+}
+
+// serviceParamsPointer is satisfied by the params of service methods, all
+// of which embed serviceParams.
+type serviceParamsPointer[P any] interface {
+	*P
+	serviceID() int64
+}
+
+func serviceMethod[P any, PP serviceParamsPointer[P], R any](name string, description string, call func(service *liboc.BoxService, params *P) (*R, error)) *rpcMethod {
+	return newMethod(name, description, func(params *P) (*R, error) {
+		service, err := loadServiceByID(PP(params).serviceID())
+		if err != nil {
+			return nil, err
+		}
+		return call(service, params)
+	})
+}
+
+var rpcMethods = []*rpcMethod{
+	newMethod("Version", "Returns the core version.", func(params *emptyParams) (*versionResult, error) {
+		return &versionResult{constant.Version}, nil
+	}),
+	newMethod("SetLocale", "Sets the locale of messages and diagnostics.", func(params *localeParams) (*emptyResult, error) {
+		liboc.SetLocale(params.Locale)
+		return &emptyResult{}, nil
+	}),
+	newMethod("SetMemoryLimit", "Enables or disables the low memory mode.", func(params *memoryLimitParams) (*emptyResult, error) {
+		liboc.SetMemoryLimit(params.Enabled)
+		return &emptyResult{}, nil
+	}),
+	newMethod("CheckConfig", "Checks that a configuration can be created.", func(params *configParams) (*emptyResult, error) {
+		err := liboc.CheckConfig(params.Config)
+		if err != nil {
+			return nil, err
+		}
+		return &emptyResult{}, nil
+	}),
+	newMethod("FormatConfig", "Returns the configuration in canonical indented form.", func(params *configParams) (*formatConfigResult, error) {
+		formatted, err := liboc.FormatConfig(params.Config)
+		if err != nil {
+			return nil, newKindError(errorKindConfig, err)
+		}
+		return &formatConfigResult{formatted.Value}, nil
+	}),
+	newMethod("ValidateConfig", "Returns every problem found in the configuration.", func(params *configParams) (*validateConfigResult, error) {
+		result := &validateConfigResult{Diagnostics: []configDiagnosticJSON{}}
+		diagnostics := liboc.ValidateConfig(params.Config)
+		for diagnostics.HasNext() {
+			diagnostic := diagnostics.Next()
+			severity := "error"
+			if diagnostic.Severity == liboc.ConfigDiagnosticWarning {
+				severity = "warning"
+			}
+			result.Diagnostics = append(result.Diagnostics, configDiagnosticJSON{
+				Severity: severity,
+				Pointer:  diagnostic.Pointer,
+				Line:     diagnostic.Line,
+				Column:   diagnostic.Column,
+				Message:  diagnostic.Message,
+			})
+		}
+		return result, nil
+	}),
+	newMethod("MigrateConfig", "Rewrites deprecated options to their current equivalents.", func(params *configParams) (*migrateConfigResult, error) {
+		migration, err := liboc.MigrateConfig(params.Config)
+		if err != nil {
+			return nil, newKindError(errorKindConfig, err)
+		}
+		return &migrateConfigResult{migration.Content, newDeprecatedNotesJSON(migration.Notes())}, nil
+	}),
+	newMethod("ListServices", "Lists the services that have not been closed.", func(params *emptyParams) (*listServicesResult, error) {
+		return &listServicesResult{listServices()}, nil
+	}),
+	serviceMethod("ServiceStart", "Starts a service.", func(service *liboc.BoxService, params *serviceParams) (*emptyResult, error) {
+		return &emptyResult{}, service.Start()
+	}),
+	newMethod("ServiceClose", "Closes a service, giving up after timeout_ms if set.", func(params *serviceCloseParams) (*emptyResult, error) {
+		return &emptyResult{}, closeService(params.ServiceID, params.TimeoutMs)
+	}),
+	serviceMethod("ServiceReload", "Replaces the configuration of a running service.", func(service *liboc.BoxService, params *serviceConfigParams) (*emptyResult, error) {
+		return &emptyResult{}, service.Reload(params.Config)
+	}),
+	serviceMethod("ServicePause", "Pauses a service when the device sleeps.", func(service *liboc.BoxService, params *serviceParams) (*emptyResult, error) {
+		service.Pause()
+		return &emptyResult{}, nil
+	}),
+	serviceMethod("ServiceWake", "Resumes a paused service.", func(service *liboc.BoxService, params *serviceParams) (*emptyResult, error) {
+		service.Wake()
+		return &emptyResult{}, nil
+	}),
+	serviceMethod("ServiceNeedWIFIState", "Reports whether the service uses the WIFI state.", func(service *liboc.BoxService, params *serviceParams) (*needWIFIStateResult, error) {
+		return &needWIFIStateResult{service.NeedWIFIState()}, nil
+	}),
+	serviceMethod("ServiceTraffic", "Returns total bytes and rates, globally and per outbound.", func(service *liboc.BoxService, params *serviceParams) (*trafficJSON, error) {
+		return newTrafficJSON(service.TrafficSnapshot()), nil
+	}),
+	serviceMethod("ServiceConnections", "Returns the active connections.", func(service *liboc.BoxService, params *serviceParams) (*connectionsResult, error) {
+		connections, err := service.Connections()
+		if err != nil {
+			return nil, err
+		}
+		return newConnectionsResult(connections), nil
+	}),
+	serviceMethod("ServiceCloseConnection", "Closes a connection.", func(service *liboc.BoxService, params *closeConnectionParams) (*emptyResult, error) {
+		return &emptyResult{}, service.CloseConnection(params.ConnectionID)
+	}),
+	serviceMethod("ServiceCloseAllConnections", "Closes every connection.", func(service *liboc.BoxService, params *serviceParams) (*emptyResult, error) {
+		service.CloseAllConnections()
+		return &emptyResult{}, nil
+	}),
+	serviceMethod("ServiceOutboundGroups", "Returns the outbound groups and their members.", func(service *liboc.BoxService, params *serviceParams) (*outboundGroupsResult, error) {
+		result := &outboundGroupsResult{Groups: []outboundGroupJSON{}}
+		groups := service.ListOutboundGroups()
+		for groups.HasNext() {
+			group := groups.Next()
+			groupJSON := outboundGroupJSON{
+				Tag:        group.Tag,
+				Type:       group.Type,
+				Selectable: group.Selectable,
+				Selected:   group.Selected,
+				Items:      []outboundGroupItemJSON{},
+			}
+			for _, item := range group.ItemList {
+				groupJSON.Items = append(groupJSON.Items, outboundGroupItemJSON(*item))
+			}
+			result.Groups = append(result.Groups, groupJSON)
+		}
+		return result, nil
+	}),
+	serviceMethod("ServiceSelectOutbound", "Switches a selector group to an outbound.", func(service *liboc.BoxService, params *selectOutboundParams) (*emptyResult, error) {
+		return &emptyResult{}, service.SelectOutbound(params.Group, params.Outbound)
+	}),
+	serviceMethod("ServiceURLTest", "Probes every member of a group in the background.", func(service *liboc.BoxService, params *urlTestParams) (*emptyResult, error) {
+		return &emptyResult{}, service.URLTest(params.Group)
+	}),
+	serviceMethod("ServiceGetClashMode", "Returns the clash mode and the available modes.", func(service *liboc.BoxService, params *serviceParams) (*clashModeResult, error) {
+		result := &clashModeResult{Mode: service.GetClashMode(), Modes: []string{}}
+		modes := service.GetClashModeList()
+		for modes.HasNext() {
+			result.Modes = append(result.Modes, modes.Next())
+		}
+		return result, nil
+	}),
+	serviceMethod("ServiceSetClashMode", "Sets the clash mode.", func(service *liboc.BoxService, params *clashModeParams) (*emptyResult, error) {
+		return &emptyResult{}, service.SetClashMode(params.Mode)
+	}),
+	serviceMethod("ServiceSetLogLevel", "Changes which log entries are kept and delivered.", func(service *liboc.BoxService, params *logLevelParams) (*emptyResult, error) {
+		return &emptyResult{}, newKindError(errorKindInvalidArgument, service.SetLogLevel(params.Level))
+	}),
+	serviceMethod("ServiceRecentLogs", "Returns the buffered log entries, oldest first.", func(service *liboc.BoxService, params *serviceParams) (*logsResult, error) {
+		return newLogsResult(service.RecentLogs()), nil
+	}),
+	serviceMethod("ServiceDeprecatedNotes", "Returns the deprecated features used by the configuration.", func(service *liboc.BoxService, params *serviceParams) (*deprecatedNotesResult, error) {
+		return &deprecatedNotesResult{newDeprecatedNotesJSON(service.DeprecatedNotes())}, nil
+	}),
+}
+
+// callMethod runs method with JSON params and returns its JSON result.
+func callMethod(method string, params []byte) ([]byte, error) {
+	index := slices.IndexFunc(rpcMethods, func(it *rpcMethod) bool {
+		return it.name == method
+	})
+	if index == -1 {
+		return nil, newKindError(errorKindNotFound, E.New("unknown method: ", method))
+	}
+	result, err := rpcMethods[index].call(params)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(result)
+}
+
+type logListenerFunc func(entries liboc.LogEntryIterator)
+
+func (f logListenerFunc) WriteLogEntries(entries liboc.LogEntryIterator) {
+	f(entries)
+}
+
+func subscribeLogs(params *serviceParams, emit func(event *logsResult)) (int64, func(), error) {
+	service, err := loadServiceByID(params.ServiceID)
+	if err != nil {
+		return 0, nil, err
+	}
+	subscription := service.SubscribeLogs(logListenerFunc(func(entries liboc.LogEntryIterator) {
+		emit(newLogsResult(entries))
+	}))
+	return params.ServiceID, subscription.Close, nil
+}
+
+type trafficListenerFunc func(snapshot *liboc.TrafficSnapshot)
+
+func (f trafficListenerFunc) WriteTraffic(snapshot *liboc.TrafficSnapshot) {
+	f(snapshot)
+}
+
+func subscribeTraffic(params *intervalParams, emit func(event *trafficJSON)) (int64, func(), error) {
+	service, err := loadServiceByID(params.ServiceID)
+	if err != nil {
+		return 0, nil, err
+	}
+	subscription := service.SubscribeTraffic(trafficListenerFunc(func(snapshot *liboc.TrafficSnapshot) {
+		emit(newTrafficJSON(snapshot))
+	}), params.IntervalMs)
+	return params.ServiceID, subscription.Close, nil
+}
+
+type connectionListenerFunc func(connections liboc.ConnectionIterator)
+
+func (f connectionListenerFunc) WriteConnections(connections liboc.ConnectionIterator) {
+	f(connections)
+}
+
+func subscribeConnections(params *intervalParams, emit func(event *connectionsResult)) (int64, func(), error) {
+	service, err := loadServiceByID(params.ServiceID)
+	if err != nil {
+		return 0, nil, err
+	}
+	subscription := service.SubscribeConnections(connectionListenerFunc(func(connections liboc.ConnectionIterator) {
+		emit(newConnectionsResult(connections))
+	}), params.IntervalMs)
+	return params.ServiceID, subscription.Close, nil
+}
+
+var rpcTopics = []*rpcTopic{
+	newTopic("logs", "Batches of new log entries.", subscribeLogs),
+	newTopic("traffic", "Traffic snapshots every interval_ms, one second by default.", subscribeTraffic),
+	newTopic("connections", "The active connections every interval_ms, one second by default, with uplink and downlink measured over each interval.", subscribeConnections),
+}
+
+type rpcSubscription struct {
+	serviceID int64
+	cancel    func()
+}
+
+var (
+	subscriptionAccess sync.Mutex
+	subscriptions      = make(map[int64]*rpcSubscription)
+	nextSubscriptionID int64
+)
+
+// subscribe starts delivering JSON events of topic to emit until
+// unsubscribe is called or the service is closed. The topic is started while
+// holding subscriptionAccess, so unsubscribe and closeServiceSubscriptions
+// never miss a subscription whose events are already being delivered. Topics
+// must not emit from the subscribing call.
+func subscribe(topic string, params []byte, emit func(subscriptionID int64, event []byte)) (int64, error) {
+	index := slices.IndexFunc(rpcTopics, func(it *rpcTopic) bool {
+		return it.name == topic
+	})
+	if index == -1 {
+		return 0, newKindError(errorKindNotFound, E.New("unknown topic: ", topic))
+	}
+	subscriptionAccess.Lock()
+	defer subscriptionAccess.Unlock()
+	nextSubscriptionID++
+	subscriptionID := nextSubscriptionID
+	serviceID, cancel, err := rpcTopics[index].subscribe(params, func(event any) {
+		content, err := json.Marshal(event)
+		if err != nil {
+			return
+		}
+		emit(subscriptionID, content)
+	})
+	if err != nil {
+		return 0, err
+	}
+	subscriptions[subscriptionID] = &rpcSubscription{serviceID, cancel}
+	return subscriptionID, nil
+}
+
+func unsubscribe(subscriptionID int64) error {
+	subscriptionAccess.Lock()
+	subscription := subscriptions[subscriptionID]
+	delete(subscriptions, subscriptionID)
+	subscriptionAccess.Unlock()
+	if subscription == nil {
+		return newKindError(errorKindNotFound, E.New("subscription not found: ", subscriptionID))
+	}
+	subscription.cancel()
+	return nil
+}
+
+// closeServiceSubscriptions cancels the subscriptions of a closed service.
+func closeServiceSubscriptions(serviceID int64) {
+	subscriptionAccess.Lock()
+	var cancels []func()
+	for subscriptionID, subscription := range subscriptions {
+		if subscription.serviceID == serviceID {
+			cancels = append(cancels, subscription.cancel)
+			delete(subscriptions, subscriptionID)
+		}
+	}
+	subscriptionAccess.Unlock()
+	for _, cancel := range cancels {
+		cancel()
+	}
+}
+
+type methodDescription struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Params      map[string]any `json:"params"`
+	Result      map[string]any `json:"result"`
+}
+
+type topicDescription struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Params      map[string]any `json:"params"`
+	Event       map[string]any `json:"event"`
+}
+
+type apiDescription struct {
+	Version    string              `json:"version"`
+	Methods    []methodDescription `json:"methods"`
+	Topics     []topicDescription  `json:"topics"`
+	ErrorCodes map[string]int32    `json:"error_codes"`
+}
+
+// describe returns the methods and topics with JSON schemas of their
+// parameters, results and events.
+func describe(errorCodes map[string]int32) *apiDescription {
+	description := &apiDescription{
+		Version:    constant.Version,
+		ErrorCodes: errorCodes,
+	}
+	for _, method := range rpcMethods {
+		description.Methods = append(description.Methods, methodDescription{
+			Name:        method.name,
+			Description: method.description,
+			Params:      jsonSchema(method.paramsType),
+			Result:      jsonSchema(method.resultType),
+		})
+	}
+	for _, topic := range rpcTopics {
+		description.Topics = append(description.Topics, topicDescription{
+			Name:        topic.name,
+			Description: topic.description,
+			Params:      jsonSchema(topic.paramsType),
+			Event:       jsonSchema(topic.eventType),
+		})
+	}
+	return description
+}
+
+func jsonSchema(schemaType reflect.Type) map[string]any {
+	switch schemaType.Kind() {
+	case reflect.Pointer:
+		return jsonSchema(schemaType.Elem())
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": jsonSchema(schemaType.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": jsonSchema(schemaType.Elem())}
+	case reflect.Struct:
+		properties := make(map[string]any)
+		required := []string{}
+		addStructProperties(schemaType, properties, &required)
+		return map[string]any{"type": "object", "properties": properties, "required": required}
+	}
+	return map[string]any{}
+}
+
+func addStructProperties(structType reflect.Type, properties map[string]any, required *[]string) {
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			addStructProperties(field.Type, properties, required)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = jsonSchema(field.Type)
+		if !slices.Contains(strings.Split(options, ","), "omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
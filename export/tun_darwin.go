@@ -0,0 +1,16 @@
+//go:build darwin && !ios && cgo
+
+package main
+
+import (
+	liboc "github.com/Open-Application/OpenCore"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+func defaultTunnelFileDescriptor() (int32, error) {
+	tunFd := liboc.GetTunnelFileDescriptor()
+	if tunFd == -1 {
+		return -1, E.New("utun file descriptor not found")
+	}
+	return tunFd, nil
+}
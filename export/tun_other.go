@@ -0,0 +1,9 @@
+//go:build (windows || linux) && !android && cgo
+
+package main
+
+import "os"
+
+func defaultTunnelFileDescriptor() (int32, error) {
+	return -1, os.ErrInvalid
+}
@@ -3,6 +3,7 @@ module github.com/Open-Application/OpenCore
 go 1.25.3
 
 require (
+	github.com/gofrs/uuid/v5 v5.3.2
 	github.com/miekg/dns v1.1.67
 	github.com/sagernet/sing v0.7.12
 	github.com/sagernet/sing-box v1.12.11
@@ -35,7 +36,6 @@ require (
 	github.com/gobwas/httphead v0.1.0 // indirect
 	github.com/gobwas/pool v0.2.1 // indirect
 	github.com/godbus/dbus/v5 v5.1.1-0.20230522191255-76236955d466 // indirect
-	github.com/gofrs/uuid/v5 v5.3.2 // indirect
 	github.com/golang/groupcache v0.0.0-20210331224755-41bb18bfe9da // indirect
 	github.com/google/btree v1.1.3 // indirect
 	github.com/google/go-cmp v0.7.0 // indirect
@@ -0,0 +1,95 @@
+package liboc
+
+import (
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/protocol/group"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+type OutboundGroup struct {
+	Tag        string
+	Type       string
+	Selectable bool
+	Selected   string
+	ItemList   []*OutboundGroupItem
+}
+
+func (g *OutboundGroup) GetItems() OutboundGroupItemIterator {
+	return newIterator(g.ItemList)
+}
+
+type OutboundGroupIterator interface {
+	Next() *OutboundGroup
+	HasNext() bool
+}
+
+type OutboundGroupItem struct {
+	Tag          string
+	Type         string
+	URLTestTime  int64
+	URLTestDelay int32
+}
+
+type OutboundGroupItemIterator interface {
+	Next() *OutboundGroupItem
+	HasNext() bool
+}
+
+// ListOutboundGroups returns every outbound group with its members and their
+// last URL test result.
+func (s *BoxService) ListOutboundGroups() OutboundGroupIterator {
+	return newPtrIterator(s.outboundGroups())
+}
+
+func (s *BoxService) outboundGroups() []OutboundGroup {
+	state := s.current()
+	outboundManager := state.instance.Outbound()
+	var groups []OutboundGroup
+	for _, it := range outboundManager.Outbounds() {
+		iGroup, isGroup := it.(adapter.OutboundGroup)
+		if !isGroup {
+			continue
+		}
+		var outboundGroup OutboundGroup
+		outboundGroup.Tag = iGroup.Tag()
+		outboundGroup.Type = iGroup.Type()
+		_, outboundGroup.Selectable = iGroup.(*group.Selector)
+		outboundGroup.Selected = iGroup.Now()
+		for _, itemTag := range iGroup.All() {
+			itemOutbound, isLoaded := outboundManager.Outbound(itemTag)
+			if !isLoaded {
+				continue
+			}
+			var item OutboundGroupItem
+			item.Tag = itemTag
+			item.Type = itemOutbound.Type()
+			if history := state.urlTestHistoryStorage.LoadURLTestHistory(adapter.OutboundTag(itemOutbound)); history != nil {
+				item.URLTestTime = history.Time.Unix()
+				item.URLTestDelay = int32(history.Delay)
+			}
+			outboundGroup.ItemList = append(outboundGroup.ItemList, &item)
+		}
+		groups = append(groups, outboundGroup)
+	}
+	return groups
+}
+
+// SelectOutbound switches a selector group to outboundTag. The selector
+// stores the choice in the cache file so it is restored on the next start.
+func (s *BoxService) SelectOutbound(groupTag string, outboundTag string) error {
+	outboundGroup, isLoaded := s.current().instance.Outbound().Outbound(groupTag)
+	if !isLoaded {
+		return E.New("selector not found: ", groupTag)
+	}
+	selector, isSelector := outboundGroup.(*group.Selector)
+	if !isSelector {
+		return E.New("outbound is not a selector: ", groupTag)
+	}
+	if !selector.SelectOutbound(outboundTag) {
+		return E.New("outbound not found in selector: ", outboundTag)
+	}
+	if s.commandServer != nil {
+		s.commandServer.notifyURLTestUpdate()
+	}
+	return nil
+}
@@ -8,7 +8,16 @@ type StringIterator interface {
 	Next() string
 }
 
-var _ StringIterator = (*iterator[string])(nil)
+type Int32Iterator interface {
+	Len() int32
+	HasNext() bool
+	Next() int32
+}
+
+var (
+	_ StringIterator = (*iterator[string])(nil)
+	_ Int32Iterator  = (*iterator[int32])(nil)
+)
 
 type iterator[T any] struct {
 	values []T
@@ -1,31 +1,109 @@
 package liboc
 
+import (
+	"reflect"
+	"sort"
+	"sync"
+)
+
 var (
-	localeRegistry = make(map[string]*Locale)
+	localeAccess   sync.RWMutex
+	localeRegistry = map[string]*Locale{defaultLocal.Locale: defaultLocal}
 	current        = defaultLocal
 )
 
+// Locale holds the user-facing messages, several of them fmt formats.
+// DeprecatedMessage takes the description, the deprecated and scheduled
+// versions and the migration link of a note.
 type Locale struct {
-	Locale                  string
-	DeprecatedMessage       string
-	DeprecatedMessageNoLink string
+	Locale                      string
+	DeprecatedMessage           string
+	DeprecatedMessageNoLink     string
+	DeprecatedNotificationTitle string
+	InvalidConfigMessage        string
+	DuplicateTagMessage         string
+	UnknownOutboundMessage      string
+	UnknownDNSServerMessage     string
+	UnknownRuleSetMessage       string
+	UnusedRuleSetMessage        string
+	ParseConfigMessage          string
+	OpenTunMessage              string
+	CreateTunMessage            string
+	QueryTunNameMessage         string
+	DupTunMessage               string
+	NoIncludePackageMessage     string
+	UIDRangeTooLargeMessage     string
+	DNSServerAddressMessage     string
 }
 
 var defaultLocal = &Locale{
-	Locale:                  "en_US",
-	DeprecatedMessage:       "%s is deprecated in liboc %s and will be removed in liboc %s please checkout documentation for migration.",
-	DeprecatedMessageNoLink: "%s is deprecated in liboc %s and will be removed in liboc %s.",
+	Locale:                      "en_US",
+	DeprecatedMessage:           "%s is deprecated in liboc %s and will be removed in liboc %s, please checkout documentation for migration: %s",
+	DeprecatedMessageNoLink:     "%s is deprecated in liboc %s and will be removed in liboc %s.",
+	DeprecatedNotificationTitle: "Deprecated configuration",
+	InvalidConfigMessage:        "Invalid configuration: %s",
+	DuplicateTagMessage:         "Tag %s is already used.",
+	UnknownOutboundMessage:      "Outbound %s is not defined.",
+	UnknownDNSServerMessage:     "DNS server %s is not defined.",
+	UnknownRuleSetMessage:       "Rule-set %s is not defined.",
+	UnusedRuleSetMessage:        "Rule-set %s is not used by any rule.",
+	ParseConfigMessage:          "parse config",
+	OpenTunMessage:              "open tun",
+	CreateTunMessage:            "failed to create TUN device",
+	QueryTunNameMessage:         "query tun name",
+	DupTunMessage:               "dup tun file descriptor",
+	NoIncludePackageMessage:     "platform: no package found for include_uid",
+	UIDRangeTooLargeMessage:     "platform: uid range %d:%d exceeds the limit of %d uids",
+	DNSServerAddressMessage:     "need one more IPv4 address for DNS hijacking",
 }
 
 func Current() *Locale {
+	localeAccess.RLock()
+	defer localeAccess.RUnlock()
 	return current
 }
 
 func Set(localeId string) bool {
+	localeAccess.Lock()
+	defer localeAccess.Unlock()
 	locale, loaded := localeRegistry[localeId]
 	if !loaded {
 		return false
 	}
 	current = locale
 	return true
-}
\ No newline at end of file
+}
+
+// RegisterLocale adds or replaces the locale identified by locale.Locale.
+// Messages left empty fall back to the default English ones.
+func RegisterLocale(locale *Locale) {
+	if locale == nil || locale.Locale == "" {
+		return
+	}
+	registered := *locale
+	value := reflect.ValueOf(&registered).Elem()
+	defaultValue := reflect.ValueOf(defaultLocal).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		if value.Field(i).String() == "" {
+			value.Field(i).Set(defaultValue.Field(i))
+		}
+	}
+	localeAccess.Lock()
+	defer localeAccess.Unlock()
+	if current.Locale == registered.Locale {
+		current = &registered
+	}
+	localeRegistry[registered.Locale] = &registered
+}
+
+// AvailableLocales returns the identifiers of registered locales, sorted.
+func AvailableLocales() StringIterator {
+	localeAccess.RLock()
+	defer localeAccess.RUnlock()
+	localeIds := make([]string, 0, len(localeRegistry))
+	for localeId := range localeRegistry {
+		localeIds = append(localeIds, localeId)
+	}
+	sort.Strings(localeIds)
+	return newIterator(localeIds)
+}
@@ -0,0 +1,24 @@
+package liboc
+
+func init() {
+	RegisterLocale(&Locale{
+		Locale:                      "es",
+		DeprecatedMessage:           "%s está obsoleto desde liboc %s y se eliminará en liboc %s; consulta la documentación para migrar: %s",
+		DeprecatedMessageNoLink:     "%s está obsoleto desde liboc %s y se eliminará en liboc %s.",
+		DeprecatedNotificationTitle: "Configuración obsoleta",
+		InvalidConfigMessage:        "Configuración no válida: %s",
+		DuplicateTagMessage:         "La etiqueta %s ya está en uso.",
+		UnknownOutboundMessage:      "La salida %s no está definida.",
+		UnknownDNSServerMessage:     "El servidor DNS %s no está definido.",
+		UnknownRuleSetMessage:       "El conjunto de reglas %s no está definido.",
+		UnusedRuleSetMessage:        "El conjunto de reglas %s no lo usa ninguna regla.",
+		ParseConfigMessage:          "analizar la configuración",
+		OpenTunMessage:              "abrir tun",
+		CreateTunMessage:            "no se pudo crear el dispositivo TUN",
+		QueryTunNameMessage:         "consultar el nombre de tun",
+		DupTunMessage:               "duplicar el descriptor de archivo de tun",
+		NoIncludePackageMessage:     "plataforma: no se encontró ningún paquete para include_uid",
+		UIDRangeTooLargeMessage:     "plataforma: el rango de uid %d:%d supera el límite de %d uid",
+		DNSServerAddressMessage:     "se necesita una dirección IPv4 más para el secuestro de DNS",
+	})
+}
@@ -0,0 +1,24 @@
+package liboc
+
+func init() {
+	RegisterLocale(&Locale{
+		Locale:                      "fa",
+		DeprecatedMessage:           "%s در liboc %s منسوخ شده است و در liboc %s حذف خواهد شد؛ برای مهاجرت به مستندات مراجعه کنید: %s",
+		DeprecatedMessageNoLink:     "%s در liboc %s منسوخ شده است و در liboc %s حذف خواهد شد.",
+		DeprecatedNotificationTitle: "پیکربندی منسوخ",
+		InvalidConfigMessage:        "پیکربندی نامعتبر: %s",
+		DuplicateTagMessage:         "برچسب %s قبلاً استفاده شده است.",
+		UnknownOutboundMessage:      "خروجی %s تعریف نشده است.",
+		UnknownDNSServerMessage:     "سرور DNS %s تعریف نشده است.",
+		UnknownRuleSetMessage:       "مجموعه قوانین %s تعریف نشده است.",
+		UnusedRuleSetMessage:        "مجموعه قوانین %s در هیچ قانونی استفاده نشده است.",
+		ParseConfigMessage:          "تجزیه پیکربندی",
+		OpenTunMessage:              "باز کردن tun",
+		CreateTunMessage:            "ایجاد دستگاه TUN ناموفق بود",
+		QueryTunNameMessage:         "دریافت نام tun",
+		DupTunMessage:               "تکثیر توصیف‌گر فایل tun",
+		NoIncludePackageMessage:     "پلتفرم: هیچ برنامه‌ای برای include_uid یافت نشد",
+		UIDRangeTooLargeMessage:     "پلتفرم: بازه uid %d:%d از حد %d uid بیشتر است",
+		DNSServerAddressMessage:     "برای ربودن DNS به یک نشانی IPv4 دیگر نیاز است",
+	})
+}
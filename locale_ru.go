@@ -0,0 +1,24 @@
+package liboc
+
+func init() {
+	RegisterLocale(&Locale{
+		Locale:                      "ru",
+		DeprecatedMessage:           "%s устарело в liboc %s и будет удалено в liboc %s, инструкции по миграции смотрите в документации: %s",
+		DeprecatedMessageNoLink:     "%s устарело в liboc %s и будет удалено в liboc %s.",
+		DeprecatedNotificationTitle: "Устаревшая конфигурация",
+		InvalidConfigMessage:        "Недопустимая конфигурация: %s",
+		DuplicateTagMessage:         "Тег %s уже используется.",
+		UnknownOutboundMessage:      "Исходящее подключение %s не определено.",
+		UnknownDNSServerMessage:     "DNS-сервер %s не определён.",
+		UnknownRuleSetMessage:       "Набор правил %s не определён.",
+		UnusedRuleSetMessage:        "Набор правил %s не используется ни одним правилом.",
+		ParseConfigMessage:          "разбор конфигурации",
+		OpenTunMessage:              "открытие tun",
+		CreateTunMessage:            "не удалось создать устройство TUN",
+		QueryTunNameMessage:         "получение имени tun",
+		DupTunMessage:               "дублирование файлового дескриптора tun",
+		NoIncludePackageMessage:     "платформа: не найдено приложение для include_uid",
+		UIDRangeTooLargeMessage:     "платформа: диапазон uid %d:%d превышает предел в %d uid",
+		DNSServerAddressMessage:     "для перехвата DNS нужен ещё один адрес IPv4",
+	})
+}
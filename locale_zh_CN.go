@@ -0,0 +1,24 @@
+package liboc
+
+func init() {
+	RegisterLocale(&Locale{
+		Locale:                      "zh_CN",
+		DeprecatedMessage:           "%s 已在 liboc %s 中被弃用，且将在 liboc %s 中被移除，请参阅迁移指南：%s",
+		DeprecatedMessageNoLink:     "%s 已在 liboc %s 中被弃用，且将在 liboc %s 中被移除。",
+		DeprecatedNotificationTitle: "配置已过时",
+		InvalidConfigMessage:        "配置无效：%s",
+		DuplicateTagMessage:         "标签 %s 已被使用。",
+		UnknownOutboundMessage:      "出站 %s 未定义。",
+		UnknownDNSServerMessage:     "DNS 服务器 %s 未定义。",
+		UnknownRuleSetMessage:       "规则集 %s 未定义。",
+		UnusedRuleSetMessage:        "规则集 %s 未被任何规则使用。",
+		ParseConfigMessage:          "解析配置",
+		OpenTunMessage:              "打开 tun",
+		CreateTunMessage:            "创建 TUN 设备失败",
+		QueryTunNameMessage:         "查询 tun 名称",
+		DupTunMessage:               "复制 tun 文件描述符",
+		NoIncludePackageMessage:     "平台：未找到 include_uid 对应的应用",
+		UIDRangeTooLargeMessage:     "平台：uid 范围 %d:%d 超过 %d 个 uid 的上限",
+		DNSServerAddressMessage:     "DNS 劫持需要额外一个 IPv4 地址",
+	})
+}
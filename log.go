@@ -0,0 +1,208 @@
+package liboc
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/log"
+)
+
+const (
+	LogLevelPanic = int32(log.LevelPanic)
+	LogLevelFatal = int32(log.LevelFatal)
+	LogLevelError = int32(log.LevelError)
+	LogLevelWarn  = int32(log.LevelWarn)
+	LogLevelInfo  = int32(log.LevelInfo)
+	LogLevelDebug = int32(log.LevelDebug)
+	LogLevelTrace = int32(log.LevelTrace)
+)
+
+const logBufferSize = 1024
+
+type LogEntry struct {
+	Level     int32
+	Message   string
+	Timestamp int64
+}
+
+type LogEntryIterator interface {
+	Next() *LogEntry
+	HasNext() bool
+}
+
+type LogListener interface {
+	WriteLogEntries(entries LogEntryIterator)
+}
+
+// logManager keeps the most recent log entries and delivers new ones to the
+// platform, the command server and the log listener from its own goroutine,
+// so a slow callback never blocks the logger. It is kept across reloads.
+type logManager struct {
+	ctx           context.Context
+	cancel        context.CancelFunc
+	iif           PlatformInterface
+	access        sync.Mutex
+	level         log.Level
+	entries       []LogEntry
+	next          int
+	full          bool
+	pending       []LogEntry
+	notify        chan struct{}
+	listener      LogListener
+	subscriptions []*LogSubscription
+	commandServer *CommandServer
+}
+
+func newLogManager(platformInterface PlatformInterface) *logManager {
+	ctx, cancel := context.WithCancel(context.Background())
+	manager := &logManager{
+		ctx:     ctx,
+		cancel:  cancel,
+		iif:     platformInterface,
+		level:   log.LevelTrace,
+		entries: make([]LogEntry, logBufferSize),
+		notify:  make(chan struct{}, 1),
+	}
+	go manager.loopDeliver()
+	return manager
+}
+
+func (m *logManager) Close() {
+	m.cancel()
+}
+
+func (m *logManager) write(level log.Level, message string) {
+	entry := LogEntry{
+		Level:     int32(level),
+		Message:   message,
+		Timestamp: time.Now().UnixMilli(),
+	}
+	m.access.Lock()
+	if level > m.level {
+		m.access.Unlock()
+		return
+	}
+	m.entries[m.next] = entry
+	m.next = (m.next + 1) % len(m.entries)
+	if m.next == 0 {
+		m.full = true
+	}
+	if len(m.pending) >= logBufferSize {
+		m.pending = m.pending[1:]
+	}
+	m.pending = append(m.pending, entry)
+	m.access.Unlock()
+	select {
+	case m.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (m *logManager) loopDeliver() {
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-m.notify:
+		}
+		m.access.Lock()
+		pending := m.pending
+		m.pending = nil
+		listener := m.listener
+		subscriptions := slices.Clone(m.subscriptions)
+		commandServer := m.commandServer
+		m.access.Unlock()
+		for _, entry := range pending {
+			m.iif.WriteLog(entry.Message)
+			if commandServer != nil {
+				commandServer.WriteMessage(entry.Message)
+			}
+		}
+		writeLogFile(pending)
+		if listener != nil {
+			listener.WriteLogEntries(newPtrIterator(pending))
+		}
+		for _, subscription := range subscriptions {
+			subscription.listener.WriteLogEntries(newPtrIterator(pending))
+		}
+	}
+}
+
+func (m *logManager) setCommandServer(commandServer *CommandServer) {
+	m.access.Lock()
+	defer m.access.Unlock()
+	m.commandServer = commandServer
+}
+
+func (m *logManager) recent() []LogEntry {
+	m.access.Lock()
+	defer m.access.Unlock()
+	if !m.full {
+		return append([]LogEntry(nil), m.entries[:m.next]...)
+	}
+	return append(append([]LogEntry(nil), m.entries[m.next:]...), m.entries[:m.next]...)
+}
+
+// SetLogListener sets the listener receiving batches of new log entries.
+func (s *BoxService) SetLogListener(listener LogListener) {
+	s.logManager.access.Lock()
+	defer s.logManager.access.Unlock()
+	s.logManager.listener = listener
+}
+
+type LogSubscription struct {
+	manager  *logManager
+	listener LogListener
+}
+
+func (s *LogSubscription) Close() {
+	s.manager.access.Lock()
+	defer s.manager.access.Unlock()
+	s.manager.subscriptions = slices.DeleteFunc(s.manager.subscriptions, func(it *LogSubscription) bool {
+		return it == s
+	})
+}
+
+// SubscribeLogs delivers batches of new log entries to listener, alongside
+// the one set with SetLogListener, until the subscription is closed.
+func (s *BoxService) SubscribeLogs(listener LogListener) *LogSubscription {
+	subscription := &LogSubscription{s.logManager, listener}
+	s.logManager.access.Lock()
+	defer s.logManager.access.Unlock()
+	s.logManager.subscriptions = append(s.logManager.subscriptions, subscription)
+	return subscription
+}
+
+// SetLogLevel changes which entries are kept and delivered. Entries more
+// verbose than the level in the configuration are never produced.
+func (s *BoxService) SetLogLevel(level string) error {
+	logLevel, err := log.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	s.logManager.access.Lock()
+	defer s.logManager.access.Unlock()
+	s.logManager.level = logLevel
+	return nil
+}
+
+// RecentLogs returns the buffered log entries, oldest first.
+func (s *BoxService) RecentLogs() LogEntryIterator {
+	return newPtrIterator(s.logManager.recent())
+}
+
+// DumpLogs formats the buffered log entries as text for bug reports.
+func (s *BoxService) DumpLogs() string {
+	var builder strings.Builder
+	for _, entry := range s.logManager.recent() {
+		builder.WriteString(formatLogEntry(entry))
+	}
+	return builder.String()
+}
+
+func formatLogEntry(entry LogEntry) string {
+	return time.UnixMilli(entry.Timestamp).Format(time.RFC3339Nano) + " " + strings.ToUpper(log.FormatLevel(log.Level(entry.Level))) + " " + entry.Message + "\n"
+}
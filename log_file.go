@@ -0,0 +1,436 @@
+package liboc
+
+import (
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+const (
+	logFileName         = "liboc.log"
+	logFileRotatedTime  = "20060102-150405.000"
+	defaultLogFileSize  = 4 * 1024 * 1024
+	defaultLogFileAge   = 7
+	defaultLogFileCount = 5
+)
+
+type LogFileOptions struct {
+	// MaxSizeBytes rotates the current file once it would grow past this size.
+	MaxSizeBytes int64
+	// MaxAgeDays rotates the current file once it has been written to for
+	// this many days, and removes rotated files older than that.
+	MaxAgeDays int32
+	// MaxFiles limits the number of rotated files kept.
+	MaxFiles int32
+	// Compress gzips rotated files.
+	Compress bool
+}
+
+type logFile struct {
+	access   sync.Mutex
+	options  LogFileOptions
+	file     *os.File
+	size     int64
+	openedAt time.Time
+
+	// rotated files are compressed and pruned one at a time by a single
+	// worker, so maintenance of consecutive rotations never overlaps.
+	workerAccess  sync.Mutex
+	workerRunning bool
+	worker        sync.WaitGroup
+	pending       []string
+}
+
+var (
+	logFileAccess sync.Mutex
+	sLogFile      *logFile
+)
+
+func logFileDir() string {
+	if sWorkingPath == "" {
+		return ""
+	}
+	return filepath.Join(sWorkingPath, "logs")
+}
+
+// EnableLogFile writes core logs of all services to sWorkingPath/logs,
+// rotating the file by size and age as described by options.
+func EnableLogFile(options *LogFileOptions) error {
+	logDir := logFileDir()
+	if logDir == "" {
+		return os.ErrInvalid
+	}
+	fileOptions := LogFileOptions{
+		MaxSizeBytes: defaultLogFileSize,
+		MaxAgeDays:   defaultLogFileAge,
+		MaxFiles:     defaultLogFileCount,
+	}
+	if options != nil {
+		if options.MaxSizeBytes > 0 {
+			fileOptions.MaxSizeBytes = options.MaxSizeBytes
+		}
+		if options.MaxAgeDays > 0 {
+			fileOptions.MaxAgeDays = options.MaxAgeDays
+		}
+		if options.MaxFiles > 0 {
+			fileOptions.MaxFiles = options.MaxFiles
+		}
+		fileOptions.Compress = options.Compress
+	}
+	err := os.MkdirAll(logDir, 0o700)
+	if err != nil {
+		return err
+	}
+	newLogFile := &logFile{options: fileOptions}
+	err = newLogFile.open()
+	if err != nil {
+		return err
+	}
+	DisableLogFile()
+	logFileAccess.Lock()
+	sLogFile = newLogFile
+	logFileAccess.Unlock()
+	newLogFile.schedule("")
+	return nil
+}
+
+func DisableLogFile() {
+	logFileAccess.Lock()
+	oldLogFile := sLogFile
+	sLogFile = nil
+	logFileAccess.Unlock()
+	if oldLogFile != nil {
+		oldLogFile.close()
+	}
+}
+
+func writeLogFile(entries []LogEntry) {
+	logFileAccess.Lock()
+	currentLogFile := sLogFile
+	logFileAccess.Unlock()
+	if currentLogFile == nil {
+		return
+	}
+	for _, entry := range entries {
+		currentLogFile.write(formatLogEntry(entry))
+	}
+}
+
+func (f *logFile) open() error {
+	file, err := os.OpenFile(filepath.Join(logFileDir(), logFileName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	if f.size > 0 {
+		f.openedAt = info.ModTime()
+	} else {
+		f.openedAt = time.Now()
+	}
+	return nil
+}
+
+// close closes the current file and waits for pending maintenance.
+func (f *logFile) close() {
+	f.access.Lock()
+	if f.file != nil {
+		f.file.Close()
+		f.file = nil
+	}
+	f.access.Unlock()
+	f.worker.Wait()
+}
+
+func (f *logFile) write(message string) {
+	f.access.Lock()
+	defer f.access.Unlock()
+	if f.file == nil {
+		return
+	}
+	if f.size > 0 && (f.size+int64(len(message)) > f.options.MaxSizeBytes || time.Since(f.openedAt) > f.maxAge()) {
+		err := f.rotate()
+		if err != nil || f.file == nil {
+			return
+		}
+	}
+	n, _ := f.file.WriteString(message)
+	f.size += int64(n)
+}
+
+func (f *logFile) maxAge() time.Duration {
+	return time.Duration(f.options.MaxAgeDays) * 24 * time.Hour
+}
+
+func (f *logFile) rotate() error {
+	f.file.Close()
+	f.file = nil
+	logDir := logFileDir()
+	rotatedPath := rotatedLogFilePath(logDir, time.Now())
+	err := os.Rename(filepath.Join(logDir, logFileName), rotatedPath)
+	if err != nil {
+		return err
+	}
+	err = f.open()
+	if err != nil {
+		return err
+	}
+	f.schedule(rotatedPath)
+	return nil
+}
+
+// rotatedLogFilePath names a rotated file after rotateTime, moving forward a
+// millisecond at a time past names already taken, plain or compressed, so a
+// rotation never overwrites an earlier one and names keep sorting by time.
+func rotatedLogFilePath(logDir string, rotateTime time.Time) string {
+	for {
+		rotatedPath := filepath.Join(logDir, "liboc-"+rotateTime.Format(logFileRotatedTime)+".log")
+		_, err := os.Lstat(rotatedPath)
+		if os.IsNotExist(err) {
+			_, err = os.Lstat(rotatedPath + ".gz")
+			if os.IsNotExist(err) {
+				return rotatedPath
+			}
+		}
+		rotateTime = rotateTime.Add(time.Millisecond)
+	}
+}
+
+// schedule queues maintenance after a rotation, compressing rotatedPath if
+// enabled and pruning rotated files. An empty rotatedPath only prunes.
+func (f *logFile) schedule(rotatedPath string) {
+	f.workerAccess.Lock()
+	defer f.workerAccess.Unlock()
+	f.pending = append(f.pending, rotatedPath)
+	if f.workerRunning {
+		return
+	}
+	f.workerRunning = true
+	f.worker.Add(1)
+	go f.loopMaintenance()
+}
+
+func (f *logFile) loopMaintenance() {
+	defer f.worker.Done()
+	for {
+		f.workerAccess.Lock()
+		if len(f.pending) == 0 {
+			f.workerRunning = false
+			f.workerAccess.Unlock()
+			return
+		}
+		rotatedPath := f.pending[0]
+		f.pending = f.pending[1:]
+		f.workerAccess.Unlock()
+		if rotatedPath != "" && f.options.Compress {
+			compressLogFile(rotatedPath)
+		}
+		f.cleanup()
+	}
+}
+
+// cleanup removes rotated files beyond the age and count limits.
+func (f *logFile) cleanup() {
+	rotatedFiles, err := listRotatedLogFiles()
+	if err != nil {
+		return
+	}
+	deadline := time.Now().Add(-f.maxAge())
+	for index, fileName := range rotatedFiles {
+		path := filepath.Join(logFileDir(), fileName)
+		if index >= int(f.options.MaxFiles) {
+			os.Remove(path)
+			continue
+		}
+		info, err := os.Stat(path)
+		if err == nil && info.ModTime().Before(deadline) {
+			os.Remove(path)
+		}
+	}
+}
+
+func compressLogFile(path string) error {
+	source, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+	destination, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	writer := gzip.NewWriter(destination)
+	_, err = io.Copy(writer, source)
+	if err == nil {
+		err = writer.Close()
+	}
+	closeErr := destination.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path + ".gz")
+		return err
+	}
+	return os.Remove(path)
+}
+
+// listRotatedLogFiles returns the names of rotated files, newest first.
+func listRotatedLogFiles() ([]string, error) {
+	entries, err := os.ReadDir(logFileDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var fileNames []string
+	for _, entry := range entries {
+		if entry.IsDir() || entry.Name() == logFileName || !strings.HasPrefix(entry.Name(), "liboc-") {
+			continue
+		}
+		if strings.HasSuffix(entry.Name(), ".log") || strings.HasSuffix(entry.Name(), ".log.gz") {
+			fileNames = append(fileNames, entry.Name())
+		}
+	}
+	slices.SortFunc(fileNames, func(a, b string) int {
+		return strings.Compare(b, a)
+	})
+	return fileNames, nil
+}
+
+// ListLogFiles returns the names of log files in sWorkingPath/logs, the
+// current file first and rotated files newest first.
+func ListLogFiles() (StringIterator, error) {
+	logDir := logFileDir()
+	if logDir == "" {
+		return nil, os.ErrInvalid
+	}
+	var fileNames []string
+	_, err := os.Stat(filepath.Join(logDir, logFileName))
+	if err == nil {
+		fileNames = append(fileNames, logFileName)
+	}
+	rotatedFiles, err := listRotatedLogFiles()
+	if err != nil {
+		return nil, err
+	}
+	fileNames = append(fileNames, rotatedFiles...)
+	return newIterator(fileNames), nil
+}
+
+// ExportLogFiles writes all log files into a zip archive at path.
+func ExportLogFiles(path string) error {
+	logDir := logFileDir()
+	if logDir == "" {
+		return os.ErrInvalid
+	}
+	fileNames, err := ListLogFiles()
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	writer := zip.NewWriter(file)
+	for fileNames.HasNext() {
+		err = exportLogFile(writer, logDir, fileNames.Next())
+		if err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = writer.Close()
+	}
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return E.Cause(err, "export log files")
+	}
+	return nil
+}
+
+func exportLogFile(writer *zip.Writer, logDir string, fileName string) error {
+	if fileName == logFileName {
+		logFileAccess.Lock()
+		currentLogFile := sLogFile
+		logFileAccess.Unlock()
+		if currentLogFile != nil {
+			currentLogFile.access.Lock()
+			defer currentLogFile.access.Unlock()
+		}
+	}
+	content, err := os.ReadFile(filepath.Join(logDir, fileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	entryWriter, err := writer.Create(fileName)
+	if err != nil {
+		return err
+	}
+	_, err = entryWriter.Write(content)
+	return err
+}
+
+// ClearLogFiles removes rotated files and truncates the current file.
+func ClearLogFiles() error {
+	logDir := logFileDir()
+	if logDir == "" {
+		return os.ErrInvalid
+	}
+	rotatedFiles, err := listRotatedLogFiles()
+	if err != nil {
+		return err
+	}
+	var errors []error
+	for _, fileName := range rotatedFiles {
+		removeErr := os.Remove(filepath.Join(logDir, fileName))
+		if removeErr != nil && !os.IsNotExist(removeErr) {
+			errors = append(errors, removeErr)
+		}
+	}
+	logFileAccess.Lock()
+	currentLogFile := sLogFile
+	logFileAccess.Unlock()
+	var currentErr error
+	if currentLogFile != nil {
+		currentLogFile.access.Lock()
+		if currentLogFile.file != nil {
+			currentErr = currentLogFile.file.Truncate(0)
+			if currentErr == nil {
+				currentLogFile.size = 0
+				currentLogFile.openedAt = time.Now()
+			}
+		}
+		currentLogFile.access.Unlock()
+	} else {
+		currentErr = os.Remove(filepath.Join(logDir, logFileName))
+		if os.IsNotExist(currentErr) {
+			currentErr = nil
+		}
+	}
+	if currentErr != nil {
+		errors = append(errors, currentErr)
+	}
+	return E.Errors(errors...)
+}
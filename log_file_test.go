@@ -0,0 +1,159 @@
+package liboc
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func enableTestLogFile(t *testing.T, options *LogFileOptions) *logFile {
+	sWorkingPath = t.TempDir()
+	err := EnableLogFile(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(DisableLogFile)
+	logFileAccess.Lock()
+	defer logFileAccess.Unlock()
+	return sLogFile
+}
+
+func listTestLogFiles(t *testing.T) []string {
+	fileNames, err := ListLogFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return iteratorToArray[string](fileNames)
+}
+
+func TestLogFileRotateBySize(t *testing.T) {
+	currentLogFile := enableTestLogFile(t, &LogFileOptions{MaxSizeBytes: 16, MaxFiles: 2})
+	for range 5 {
+		currentLogFile.write("0123456789abcdef\n")
+	}
+	currentLogFile.worker.Wait()
+	fileNames := listTestLogFiles(t)
+	if len(fileNames) != 3 || fileNames[0] != logFileName {
+		t.Fatalf("expected the current file and 2 rotated files, got %v", fileNames)
+	}
+	if fileNames[1] <= fileNames[2] {
+		t.Fatalf("expected rotated files newest first, got %v", fileNames)
+	}
+}
+
+func TestLogFileRotateByAge(t *testing.T) {
+	currentLogFile := enableTestLogFile(t, &LogFileOptions{MaxAgeDays: 1})
+	currentLogFile.write("old\n")
+	currentLogFile.access.Lock()
+	currentLogFile.openedAt = time.Now().AddDate(0, 0, -2)
+	currentLogFile.access.Unlock()
+	currentLogFile.write("new\n")
+	currentLogFile.worker.Wait()
+	fileNames := listTestLogFiles(t)
+	if len(fileNames) != 2 {
+		t.Fatalf("expected the current file and 1 rotated file, got %v", fileNames)
+	}
+	content, err := os.ReadFile(filepath.Join(logFileDir(), logFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "new\n" {
+		t.Fatalf("unexpected current file content %q", content)
+	}
+}
+
+func TestLogFileRotateCompress(t *testing.T) {
+	currentLogFile := enableTestLogFile(t, &LogFileOptions{MaxSizeBytes: 4, Compress: true})
+	for range 3 {
+		currentLogFile.write("line\n")
+	}
+	currentLogFile.worker.Wait()
+	fileNames := listTestLogFiles(t)
+	if len(fileNames) != 3 {
+		t.Fatalf("expected the current file and 2 rotated files, got %v", fileNames)
+	}
+	for _, fileName := range fileNames[1:] {
+		if !strings.HasSuffix(fileName, ".log.gz") {
+			t.Fatalf("expected compressed rotated files, got %v", fileNames)
+		}
+	}
+}
+
+func TestRotatedLogFilePathUnique(t *testing.T) {
+	logDir := t.TempDir()
+	rotateTime := time.Now()
+	firstPath := rotatedLogFilePath(logDir, rotateTime)
+	err := os.WriteFile(firstPath+".gz", nil, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secondPath := rotatedLogFilePath(logDir, rotateTime)
+	if secondPath <= firstPath {
+		t.Fatalf("expected a later name than %s, got %s", firstPath, secondPath)
+	}
+}
+
+func TestLogFileCleanupByAge(t *testing.T) {
+	currentLogFile := enableTestLogFile(t, &LogFileOptions{MaxAgeDays: 1})
+	currentLogFile.worker.Wait()
+	logDir := logFileDir()
+	oldPath := filepath.Join(logDir, "liboc-20000101-000000.000.log")
+	newPath := rotatedLogFilePath(logDir, time.Now())
+	for _, path := range []string{oldPath, newPath} {
+		err := os.WriteFile(path, []byte("line\n"), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	oldTime := time.Now().AddDate(0, 0, -2)
+	err := os.Chtimes(oldPath, oldTime, oldTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	currentLogFile.cleanup()
+	_, err = os.Stat(oldPath)
+	if !os.IsNotExist(err) {
+		t.Fatal("expected the expired rotated file to be removed")
+	}
+	_, err = os.Stat(newPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClearLogFiles(t *testing.T) {
+	currentLogFile := enableTestLogFile(t, &LogFileOptions{MaxSizeBytes: 4})
+	for range 3 {
+		currentLogFile.write("line\n")
+	}
+	currentLogFile.worker.Wait()
+	err := ClearLogFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileNames := listTestLogFiles(t)
+	if len(fileNames) != 1 || fileNames[0] != logFileName {
+		t.Fatalf("expected only the current file, got %v", fileNames)
+	}
+	info, err := os.Stat(filepath.Join(logFileDir(), logFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != 0 {
+		t.Fatalf("expected the current file to be truncated, got %d bytes", info.Size())
+	}
+	DisableLogFile()
+	err = ClearLogFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listTestLogFiles(t)) != 0 {
+		t.Fatal("expected no log files")
+	}
+	err = ClearLogFiles()
+	if err != nil {
+		t.Fatalf("expected clearing an empty directory to succeed, got %v", err)
+	}
+}
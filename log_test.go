@@ -0,0 +1,75 @@
+package liboc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/log"
+)
+
+type testLogPlatform struct {
+	PlatformInterface
+}
+
+func (p *testLogPlatform) WriteLog(message string) {
+}
+
+type testLogClientHandler struct {
+	CommandClientHandler
+	logs chan string
+}
+
+func (h *testLogClientHandler) Connected() {
+}
+
+func (h *testLogClientHandler) Disconnected(message string) {
+}
+
+func (h *testLogClientHandler) ClearLogs() {
+}
+
+func (h *testLogClientHandler) WriteLogs(messageList StringIterator) {
+	for messageList.HasNext() {
+		h.logs <- messageList.Next()
+	}
+}
+
+func TestCoreLogReachesCommandClient(t *testing.T) {
+	server := startTestCommandServer(t)
+	manager := newLogManager(&testLogPlatform{})
+	defer manager.Close()
+	boxService := &BoxService{logManager: manager}
+	boxService.state.Store(&serviceState{})
+	server.SetService(boxService)
+
+	handler := &testLogClientHandler{logs: make(chan string, 16)}
+	client := NewCommandClient(handler, &CommandClientOptions{
+		Command:        CommandLog,
+		StatusInterval: int64(10 * time.Millisecond),
+	})
+	err := client.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Disconnect()
+
+	const message = "router: updated default interface"
+	wrapper := &platformInterfaceWrapper{logManager: manager}
+	timeout := time.After(5 * time.Second)
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	wrapper.WriteMessage(log.LevelInfo, message)
+	for {
+		select {
+		case line := <-handler.logs:
+			if line == message {
+				return
+			}
+		case <-ticker.C:
+			// The client may subscribe after the first line is delivered.
+			wrapper.WriteMessage(log.LevelInfo, message)
+		case <-timeout:
+			t.Fatal("log line not received by command client")
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package liboc
 
 import (
+	"sync"
+
 	"github.com/sagernet/sing-tun"
 	"github.com/sagernet/sing/common/control"
 	E "github.com/sagernet/sing/common/exceptions"
@@ -11,6 +13,7 @@ import (
 var (
 	_ tun.DefaultInterfaceMonitor = (*platformDefaultInterfaceMonitor)(nil)
 	_ InterfaceUpdateListener     = (*platformDefaultInterfaceMonitor)(nil)
+	_ InterfaceUpdateListener     = (*defaultInterfaceListener)(nil)
 )
 
 type platformDefaultInterfaceMonitor struct {
@@ -22,11 +25,11 @@ type platformDefaultInterfaceMonitor struct {
 }
 
 func (m *platformDefaultInterfaceMonitor) Start() error {
-	return m.iif.StartDefaultInterfaceMonitor(m)
+	return m.interfaceListener.attach(m)
 }
 
 func (m *platformDefaultInterfaceMonitor) Close() error {
-	return m.iif.CloseDefaultInterfaceMonitor(m)
+	return m.interfaceListener.detach(m)
 }
 
 func (m *platformDefaultInterfaceMonitor) DefaultInterface() *control.Interface {
@@ -69,6 +72,7 @@ func (m *platformDefaultInterfaceMonitor) UpdateDefaultInterface(interfaceName s
 }
 
 func (m *platformDefaultInterfaceMonitor) updateDefaultInterface(interfaceName string, interfaceIndex32 int32, isExpensive bool, isConstrained bool) {
+	defer catchPanic("default interface monitor", "", nil)
 	m.isExpensive = isExpensive
 	m.isConstrained = isConstrained
 	err := m.networkManager.UpdateInterfaces()
@@ -114,4 +118,93 @@ func (m *platformDefaultInterfaceMonitor) MyInterface() string {
 	m.defaultInterfaceAccess.Lock()
 	defer m.defaultInterfaceAccess.Unlock()
 	return m.myInterface
-}
\ No newline at end of file
+}
+
+// defaultInterfaceListener is the listener registered with the platform. It
+// outlives a single box instance so that Reload can move the default
+// interface monitor to the new instance without restarting it on the host.
+type defaultInterfaceListener struct {
+	iif        PlatformInterface
+	access     sync.Mutex
+	monitor    *platformDefaultInterfaceMonitor
+	started    bool
+	holding    bool
+	lastUpdate *defaultInterfaceUpdate
+}
+
+type defaultInterfaceUpdate struct {
+	interfaceName  string
+	interfaceIndex int32
+	isExpensive    bool
+	isConstrained  bool
+}
+
+func (l *defaultInterfaceListener) UpdateDefaultInterface(interfaceName string, interfaceIndex int32, isExpensive bool, isConstrained bool) {
+	l.access.Lock()
+	l.lastUpdate = &defaultInterfaceUpdate{interfaceName, interfaceIndex, isExpensive, isConstrained}
+	monitor := l.monitor
+	l.access.Unlock()
+	if monitor != nil {
+		monitor.UpdateDefaultInterface(interfaceName, interfaceIndex, isExpensive, isConstrained)
+	}
+}
+
+func (l *defaultInterfaceListener) attach(monitor *platformDefaultInterfaceMonitor) error {
+	l.access.Lock()
+	l.monitor = monitor
+	if l.started {
+		lastUpdate := l.lastUpdate
+		l.access.Unlock()
+		if lastUpdate != nil {
+			monitor.UpdateDefaultInterface(lastUpdate.interfaceName, lastUpdate.interfaceIndex, lastUpdate.isExpensive, lastUpdate.isConstrained)
+		}
+		return nil
+	}
+	l.started = true
+	l.access.Unlock()
+	err := l.iif.StartDefaultInterfaceMonitor(l)
+	if err != nil {
+		l.access.Lock()
+		l.started = false
+		l.monitor = nil
+		l.access.Unlock()
+	}
+	return err
+}
+
+func (l *defaultInterfaceListener) detach(monitor *platformDefaultInterfaceMonitor) error {
+	l.access.Lock()
+	if l.monitor != monitor {
+		l.access.Unlock()
+		return nil
+	}
+	l.monitor = nil
+	if l.holding || !l.started {
+		l.access.Unlock()
+		return nil
+	}
+	l.started = false
+	l.lastUpdate = nil
+	l.access.Unlock()
+	return l.iif.CloseDefaultInterfaceMonitor(l)
+}
+
+// hold keeps the platform monitor running while no instance is attached.
+func (l *defaultInterfaceListener) hold() {
+	l.access.Lock()
+	defer l.access.Unlock()
+	l.holding = true
+}
+
+func (l *defaultInterfaceListener) release() {
+	l.access.Lock()
+	l.holding = false
+	if l.monitor != nil || !l.started {
+		l.access.Unlock()
+		return
+	}
+	l.started = false
+	l.lastUpdate = nil
+	l.access.Unlock()
+	l.iif.CloseDefaultInterfaceMonitor(l)
+}
@@ -1,5 +1,7 @@
 package liboc
 
+import "github.com/sagernet/sing-tun"
+
 const (
 	InterfaceTypeWIFI     = int32(0)
 	InterfaceTypeCellular = int32(1)
@@ -35,6 +37,24 @@ type PlatformInterface interface {
 	SendNotification(notification *Notification) error
 }
 
+// NativeTunPlatformInterface may be implemented by a PlatformInterface to
+// let liboc create and configure the tun device itself through sing-tun, as
+// on Windows, instead of using the file descriptor returned by OpenTun. OpenTun
+// is still called to inform the host.
+type NativeTunPlatformInterface interface {
+	UseNativeTun() bool
+}
+
+// TunTrackingPlatformInterface may be implemented by a
+// NativeTunPlatformInterface to be handed every tun device liboc creates, so
+// that the host can identify it by the returned handle and close it if the
+// service leaks. UntrackTun is called once the instance owning the device has
+// closed it. It uses Go types and is only meant for Go hosts such as the C FFI.
+type TunTrackingPlatformInterface interface {
+	TrackTun(device tun.Tun) int32
+	UntrackTun(handle int32)
+}
+
 type InterfaceUpdateListener interface {
 	UpdateDefaultInterface(interfaceName string, interfaceIndex int32, isExpensive bool, isConstrained bool)
 }
@@ -2,6 +2,7 @@ package liboc
 
 import (
 	"context"
+	"fmt"
 	"net/netip"
 	"runtime"
 	"sync"
@@ -19,6 +20,7 @@ import (
 	E "github.com/sagernet/sing/common/exceptions"
 	"github.com/sagernet/sing/common/logger"
 	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/common/ranges"
 )
 
 var (
@@ -29,12 +31,39 @@ var (
 type platformInterfaceWrapper struct {
 	iif                    PlatformInterface
 	useProcFS              bool
+	useNativeTun           bool
 	networkManager         adapter.NetworkManager
 	myTunName              string
 	defaultInterfaceAccess sync.Mutex
 	defaultInterface       *control.Interface
 	isExpensive            bool
 	isConstrained          bool
+	interfaceListener      *defaultInterfaceListener
+	tunFd                  int32
+	tunFingerprint         string
+	trackedTuns            []int32
+	logManager             *logManager
+}
+
+func newPlatformInterfaceWrapper(platformInterface PlatformInterface, previous *platformInterfaceWrapper) *platformInterfaceWrapper {
+	wrapper := &platformInterfaceWrapper{
+		iif:       platformInterface,
+		useProcFS: platformInterface.UseProcFS(),
+		tunFd:     -1,
+	}
+	if runtime.GOOS == "windows" {
+		wrapper.useNativeTun = true
+	} else if nativeTunInterface, isNativeTun := platformInterface.(NativeTunPlatformInterface); isNativeTun {
+		wrapper.useNativeTun = nativeTunInterface.UseNativeTun()
+	}
+	if previous != nil {
+		wrapper.interfaceListener = previous.interfaceListener
+		wrapper.tunFd = previous.tunFd
+		wrapper.tunFingerprint = previous.tunFingerprint
+	} else {
+		wrapper.interfaceListener = &defaultInterfaceListener{iif: platformInterface}
+	}
+	return wrapper
 }
 
 func (w *platformInterfaceWrapper) Initialize(networkManager adapter.NetworkManager) error {
@@ -51,15 +80,26 @@ func (w *platformInterfaceWrapper) AutoDetectInterfaceControl(fd int) error {
 }
 
 func (w *platformInterfaceWrapper) OpenTun(options *tun.Options, platformOptions option.TunPlatformOptions) (tun.Tun, error) {
-	if len(options.IncludeUID) > 0 || len(options.ExcludeUID) > 0 {
-		return nil, E.New("platform: unsupported uid options")
+	if len(options.IncludeUID) > 0 {
+		includePackage, err := w.packageNamesByUIDRanges(options.IncludeUID)
+		if err != nil {
+			return nil, err
+		}
+		if len(includePackage) == 0 {
+			return nil, E.New(Current().NoIncludePackageMessage)
+		}
+		options.IncludePackage = common.Uniq(append(options.IncludePackage, includePackage...))
 	}
-	if len(options.IncludeAndroidUser) > 0 {
-		return nil, E.New("platform: unsupported android_user option")
+	excludePackage, err := w.packageNamesByUIDRanges(options.ExcludeUID)
+	if err != nil {
+		return nil, err
 	}
+	options.ExcludePackage = common.Uniq(append(options.ExcludePackage, excludePackage...))
 
-	if runtime.GOOS == "windows" {
-		options.Name = "OpenApp-TUN"
+	if w.useNativeTun {
+		if runtime.GOOS == "windows" {
+			options.Name = "OpenApp-TUN"
+		}
 		w.myTunName = options.Name
 
 		routeRanges, err := options.BuildAutoRouteRanges(true)
@@ -73,7 +113,10 @@ func (w *platformInterfaceWrapper) OpenTun(options *tun.Options, platformOptions
 
 		tunDevice, err := tun.New(*options)
 		if err != nil {
-			return nil, E.Cause(err, "failed to create Windows TUN device")
+			return nil, E.Cause(err, Current().CreateTunMessage)
+		}
+		if tunTracker, isTracker := w.iif.(TunTrackingPlatformInterface); isTracker {
+			w.trackedTuns = append(w.trackedTuns, tunTracker.TrackTun(tunDevice))
 		}
 
 		_, _ = w.iif.OpenTun(&tunOptions{options, routeRanges, platformOptions})
@@ -85,25 +128,72 @@ func (w *platformInterfaceWrapper) OpenTun(options *tun.Options, platformOptions
 	if err != nil {
 		return nil, err
 	}
-	tunFd, err := w.iif.OpenTun(&tunOptions{options, routeRanges, platformOptions})
-	if err != nil {
-		return nil, err
+	fingerprint := tunOptionsFingerprint(options, routeRanges, platformOptions)
+	tunFd := w.tunFd
+	if tunFd == -1 || fingerprint != w.tunFingerprint {
+		tunFd, err = w.iif.OpenTun(&tunOptions{options, routeRanges, platformOptions})
+		if err != nil {
+			return nil, E.Cause(err, Current().OpenTunMessage)
+		}
+		w.tunFd = tunFd
+		w.tunFingerprint = fingerprint
 	}
 
 	options.Name, err = getTunnelName(tunFd)
 	if err != nil {
-		return nil, E.Cause(err, "query tun name")
+		return nil, E.Cause(err, Current().QueryTunNameMessage)
 	}
 	options.InterfaceMonitor.RegisterMyInterface(options.Name)
 	dupFd, err := dup(int(tunFd))
 	if err != nil {
-		return nil, E.Cause(err, "dup tun file descriptor")
+		return nil, E.Cause(err, Current().DupTunMessage)
 	}
 	options.FileDescriptor = dupFd
 	w.myTunName = options.Name
 	return tun.New(*options)
 }
 
+// untrackTuns releases the native tun devices of a closed instance.
+func (w *platformInterfaceWrapper) untrackTuns() {
+	tunTracker, isTracker := w.iif.(TunTrackingPlatformInterface)
+	if !isTracker {
+		return
+	}
+	for _, handle := range w.trackedTuns {
+		tunTracker.UntrackTun(handle)
+	}
+	w.trackedTuns = nil
+}
+
+// maxUIDRangeSize bounds how many uids packageNamesByUIDRanges looks up, one
+// platform call each. It covers the whole uid space of an Android user.
+const maxUIDRangeSize = 100000
+
+// packageNamesByUIDRanges translates uid ranges into package names, since
+// platform VPN APIs only accept per-application rules by package.
+func (w *platformInterfaceWrapper) packageNamesByUIDRanges(uidRanges []ranges.Range[uint32]) ([]string, error) {
+	var uidCount uint64
+	for _, uidRange := range uidRanges {
+		if uidRange.End >= uidRange.Start {
+			uidCount += uint64(uidRange.End) - uint64(uidRange.Start) + 1
+		}
+		if uidCount > maxUIDRangeSize {
+			return nil, fmt.Errorf(Current().UIDRangeTooLargeMessage, uidRange.Start, uidRange.End, maxUIDRangeSize)
+		}
+	}
+	var packageNames []string
+	for _, uidRange := range uidRanges {
+		for uid := uint64(uidRange.Start); uid <= uint64(uidRange.End); uid++ {
+			packageName, err := w.iif.PackageNameByUid(int32(uid))
+			if err != nil || packageName == "" {
+				continue
+			}
+			packageNames = append(packageNames, packageName)
+		}
+	}
+	return packageNames, nil
+}
+
 func (w *platformInterfaceWrapper) CreateDefaultInterfaceMonitor(logger logger.Logger) tun.DefaultInterfaceMonitor {
 	return &platformDefaultInterfaceMonitor{
 		platformInterfaceWrapper: w,
@@ -197,10 +287,9 @@ func (w *platformInterfaceWrapper) DisableColors() bool {
 }
 
 func (w *platformInterfaceWrapper) WriteMessage(level log.Level, message string) {
-	w.iif.WriteLog(message)
+	w.logManager.write(level, message)
 }
 
 func (w *platformInterfaceWrapper) SendNotification(notification *platform.Notification) error {
 	return w.iif.SendNotification((*Notification)(notification))
 }
-
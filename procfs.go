@@ -1,7 +1,167 @@
 package liboc
 
-import "net/netip"
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 
+	N "github.com/sagernet/sing/common/network"
+)
+
+var procNetPath = "/proc/net"
+
+const (
+	procMatchNone = iota
+	procMatchWildcard
+	procMatchUnconnected
+	procMatchExact
+)
+
+// ResolveSocketByProcSearch returns the uid owning the socket with the given
+// tuple, or -1 if no socket matches. An exact source and destination match is
+// preferred; for UDP, unconnected sockets bound to the source address and then
+// to the wildcard address are accepted as fallbacks.
 func ResolveSocketByProcSearch(network string, source netip.AddrPort, destination netip.AddrPort) int32 {
-	return -1
-}
\ No newline at end of file
+	var protocol string
+	switch N.NetworkName(network) {
+	case N.NetworkTCP:
+		protocol = "tcp"
+	case N.NetworkUDP:
+		protocol = "udp"
+	default:
+		return -1
+	}
+	source = netip.AddrPortFrom(source.Addr().Unmap(), source.Port())
+	destination = netip.AddrPortFrom(destination.Addr().Unmap(), destination.Port())
+	if !source.Addr().IsValid() {
+		return -1
+	}
+	fileNames := []string{protocol + "6"}
+	if source.Addr().Is4() {
+		fileNames = []string{protocol, protocol + "6"}
+	}
+	var (
+		bestMatch = procMatchNone
+		bestUid   = int32(-1)
+	)
+	for _, fileName := range fileNames {
+		match, uid := searchProcNetFile(filepath.Join(procNetPath, fileName), protocol == "udp", source, destination)
+		if match > bestMatch {
+			bestMatch = match
+			bestUid = uid
+		}
+		if bestMatch == procMatchExact {
+			break
+		}
+	}
+	return bestUid
+}
+
+func searchProcNetFile(path string, isUDP bool, source netip.AddrPort, destination netip.AddrPort) (int, int32) {
+	file, err := os.Open(path)
+	if err != nil {
+		return procMatchNone, -1
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	if !scanner.Scan() {
+		return procMatchNone, -1
+	}
+	localIndex, remoteIndex, uidIndex := procNetColumns(scanner.Text())
+	var (
+		bestMatch = procMatchNone
+		bestUid   = int32(-1)
+	)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) <= localIndex || len(fields) <= remoteIndex || len(fields) <= uidIndex {
+			continue
+		}
+		local, err := parseProcNetAddress(fields[localIndex])
+		if err != nil || local.Port() != source.Port() {
+			continue
+		}
+		remote, err := parseProcNetAddress(fields[remoteIndex])
+		if err != nil {
+			continue
+		}
+		var match int
+		unconnected := remote.Addr().IsUnspecified() && remote.Port() == 0
+		switch {
+		case local.Addr() == source.Addr() && (remote == destination || !destination.IsValid()):
+			match = procMatchExact
+		case !isUDP || !unconnected:
+			continue
+		case local.Addr() == source.Addr():
+			match = procMatchUnconnected
+		case local.Addr().IsUnspecified():
+			match = procMatchWildcard
+		default:
+			continue
+		}
+		if match <= bestMatch {
+			continue
+		}
+		uid, err := strconv.ParseUint(fields[uidIndex], 10, 32)
+		if err != nil {
+			continue
+		}
+		bestMatch = match
+		bestUid = int32(uid)
+		if bestMatch == procMatchExact {
+			break
+		}
+	}
+	return bestMatch, bestUid
+}
+
+// procNetColumns maps header names to row field indexes. The header splits
+// "tx_queue rx_queue" and "tr tm->when" into two names each, while rows join
+// them with a colon.
+func procNetColumns(header string) (localIndex int, remoteIndex int, uidIndex int) {
+	localIndex, remoteIndex, uidIndex = 1, 2, 7
+	var offset int
+	for index, column := range strings.Fields(header) {
+		switch column {
+		case "rx_queue", "tm->when":
+			offset--
+		case "local_address":
+			localIndex = index + offset
+		case "rem_address":
+			remoteIndex = index + offset
+		case "uid":
+			uidIndex = index + offset
+		}
+	}
+	return
+}
+
+// parseProcNetAddress decodes an address such as "0100007F:0035", where the
+// IP is stored as native-endian 32-bit words and the port is big-endian.
+func parseProcNetAddress(value string) (netip.AddrPort, error) {
+	addressHex, portHex, found := strings.Cut(value, ":")
+	if !found {
+		return netip.AddrPort{}, os.ErrInvalid
+	}
+	addressBytes, err := hex.DecodeString(addressHex)
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+	if len(addressBytes) != 4 && len(addressBytes) != 16 {
+		return netip.AddrPort{}, os.ErrInvalid
+	}
+	for i := 0; i < len(addressBytes); i += 4 {
+		binary.BigEndian.PutUint32(addressBytes[i:], binary.NativeEndian.Uint32(addressBytes[i:]))
+	}
+	address, _ := netip.AddrFromSlice(addressBytes)
+	port, err := strconv.ParseUint(portHex, 16, 16)
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+	return netip.AddrPortFrom(address.Unmap(), uint16(port)), nil
+}
@@ -0,0 +1,43 @@
+package liboc
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"testing"
+)
+
+func TestResolveSocketByProcSearch(t *testing.T) {
+	if binary.NativeEndian.Uint16([]byte{1, 0}) != 1 {
+		t.Skip("fixtures are little-endian")
+	}
+	procNetPath = "testdata/procnet"
+	defer func() {
+		procNetPath = "/proc/net"
+	}()
+	for _, testCase := range []struct {
+		name        string
+		network     string
+		source      string
+		destination string
+		uid         int32
+	}{
+		{"exact tcp", "tcp", "10.0.0.2:40000", "1.1.1.1:443", 10100},
+		{"v4-mapped in tcp6", "tcp", "10.0.0.3:40001", "8.8.8.8:443", 10101},
+		{"v4-mapped source", "tcp", "[::ffff:10.0.0.3]:40001", "[::ffff:8.8.8.8]:443", 10101},
+		{"exact tcp6", "tcp", "[2001:db8::1]:40002", "[2001:db8::2]:443", 10102},
+		{"connected udp in udp6", "udp", "10.0.0.2:7000", "9.9.9.9:53", 10300},
+		{"unconnected udp", "udp", "10.0.0.2:5353", "224.0.0.251:5353", 10200},
+		{"wildcard udp", "udp", "10.0.0.9:6000", "1.1.1.1:53", 10202},
+		{"tcp ignores listener", "tcp", "10.0.0.5:40000", "1.1.1.1:443", -1},
+		{"wrong destination", "tcp", "10.0.0.2:40000", "1.0.0.1:443", -1},
+		{"unknown port", "udp", "10.0.0.2:9999", "1.1.1.1:53", -1},
+		{"unknown network", "icmp", "10.0.0.2:40000", "1.1.1.1:443", -1},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			uid := ResolveSocketByProcSearch(testCase.network, netip.MustParseAddrPort(testCase.source), netip.MustParseAddrPort(testCase.destination))
+			if uid != testCase.uid {
+				t.Errorf("expected uid %d, got %d", testCase.uid, uid)
+			}
+		})
+	}
+}
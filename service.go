@@ -4,6 +4,8 @@ import (
 	"context"
 	"os"
 	"runtime/debug"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/sagernet/sing-box"
@@ -11,10 +13,13 @@ import (
 	"github.com/sagernet/sing-box/common/urltest"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/dns"
+	"github.com/sagernet/sing-box/experimental/clashapi"
+	"github.com/sagernet/sing-box/experimental/deprecated"
 	"github.com/sagernet/sing-box/experimental/libbox/platform"
 	"github.com/sagernet/sing-box/include"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-box/protocol/group"
 	E "github.com/sagernet/sing/common/exceptions"
 	"github.com/sagernet/sing/common/json"
 	"github.com/sagernet/sing/service"
@@ -23,17 +28,51 @@ import (
 )
 
 type BoxService struct {
+	state             atomic.Pointer[serviceState]
+	access            sync.Mutex
+	closed            bool
+	platformInterface PlatformInterface
+	commandServer     *CommandServer
+	trafficTracker    *trafficTracker
+	logManager        *logManager
+	urlTestListener   URLTestListener
+	clashModeListener ClashModeListener
+}
+
+// serviceState holds everything that belongs to one running instance.
+// Reload publishes a new state atomically, so callers must load it once
+// with current and use that value for the whole operation.
+type serviceState struct {
 	ctx                   context.Context
 	cancel                context.CancelFunc
 	urlTestHistoryStorage adapter.URLTestHistoryStorage
 	instance              *box.Box
 	clashServer           adapter.ClashServer
 	pauseManager          pause.Manager
+	platformWrapper       *platformInterfaceWrapper
+	configContent         string
+	deprecatedManager     *deprecatedManager
 }
 
 func NewService(configContent string, platformInterface PlatformInterface) (*BoxService, error) {
+	boxService, err := newService(configContent, platformInterface, nil)
+	if err != nil {
+		return nil, err
+	}
+	boxService.watchClashMode(boxService.current())
+	return boxService, nil
+}
+
+func (s *BoxService) current() *serviceState {
+	return s.state.Load()
+}
+
+// newService creates a service instance; when previous is set the new
+// instance inherits its platform handles and traffic counters.
+func newService(configContent string, platformInterface PlatformInterface, previous *BoxService) (*BoxService, error) {
 	ctx := BaseContext(platformInterface)
-	service.MustRegister[DeprecatedManager](ctx, new(deprecatedManager))
+	deprecatedManager := &deprecatedManager{platformInterface: platformInterface}
+	service.MustRegister[deprecated.Manager](ctx, deprecatedManager)
 	options, err := parseConfig(ctx, configContent)
 	if err != nil {
 		return nil, err
@@ -42,10 +81,21 @@ func NewService(configContent string, platformInterface PlatformInterface) (*Box
 	ctx, cancel := context.WithCancel(ctx)
 	urlTestHistoryStorage := urltest.NewHistoryStorage()
 	ctx = service.ContextWithPtr(ctx, urlTestHistoryStorage)
-	platformWrapper := &platformInterfaceWrapper{
-		iif:       platformInterface,
-		useProcFS: platformInterface.UseProcFS(),
+	var (
+		previousWrapper *platformInterfaceWrapper
+		trafficTracker  *trafficTracker
+		logManager      *logManager
+	)
+	if previous != nil {
+		previousWrapper = previous.current().platformWrapper
+		trafficTracker = previous.trafficTracker
+		logManager = previous.logManager
+	} else {
+		trafficTracker = newTrafficTracker()
+		logManager = newLogManager(platformInterface)
 	}
+	platformWrapper := newPlatformInterfaceWrapper(platformInterface, previousWrapper)
+	platformWrapper.logManager = logManager
 	service.MustRegister[platform.Interface](ctx, platformWrapper)
 	instance, err := box.New(box.Options{
 		Context:           ctx,
@@ -54,8 +104,13 @@ func NewService(configContent string, platformInterface PlatformInterface) (*Box
 	})
 	if err != nil {
 		cancel()
+		if previous == nil {
+			trafficTracker.Close()
+			logManager.Close()
+		}
 		return nil, E.Cause(err, "create service")
 	}
+	instance.Router().AppendTracker(trafficTracker)
 
 	networkManager := service.FromContext[adapter.NetworkManager](ctx)
 	if networkManager != nil {
@@ -63,69 +118,231 @@ func NewService(configContent string, platformInterface PlatformInterface) (*Box
 	}
 
 	debug.FreeOSMemory()
-	return &BoxService{
+	boxService := &BoxService{
+		platformInterface: platformInterface,
+		trafficTracker:    trafficTracker,
+		logManager:        logManager,
+	}
+	boxService.state.Store(&serviceState{
 		ctx:                   ctx,
 		cancel:                cancel,
 		instance:              instance,
 		urlTestHistoryStorage: urlTestHistoryStorage,
 		pauseManager:          service.FromContext[pause.Manager](ctx),
 		clashServer:           service.FromContext[adapter.ClashServer](ctx),
-	}, nil
+		platformWrapper:       platformWrapper,
+		configContent:         configContent,
+		deprecatedManager:     deprecatedManager,
+	})
+	return boxService, nil
 }
 
-func (s *BoxService) Start() error {
+func (s *BoxService) Start() (err error) {
+	state := s.current()
 	if sFixAndroidStack {
-		var err error
 		done := make(chan struct{})
 		go func() {
-			err = s.instance.Start()
-			close(done)
+			defer close(done)
+			defer catchPanic("start", state.configContent, &err)
+			err = state.instance.Start()
 		}()
 		<-done
-		return err
-	} else {
-		return s.instance.Start()
+		return
 	}
+	defer catchPanic("start", state.configContent, &err)
+	return state.instance.Start()
 }
 
+// Close stops the service, giving up after C.FatalStopTimeout. See
+// CloseWithTimeout for what happens when the deadline is exceeded.
 func (s *BoxService) Close() error {
+	return s.closeWithTimeout(C.FatalStopTimeout)
+}
+
+// CloseWithTimeout stops the service, giving up after timeoutMs milliseconds
+// (or C.FatalStopTimeout if timeoutMs is not positive). If the deadline is
+// exceeded a *CloseTimeoutError is returned while the remaining components
+// keep closing in the background, unless SetupOptions.ExitOnCloseTimeout is
+// set, in which case the process exits.
+func (s *BoxService) CloseWithTimeout(timeoutMs int32) error {
+	timeout := C.FatalStopTimeout
+	if timeoutMs > 0 {
+		timeout = time.Duration(timeoutMs) * time.Millisecond
+	}
+	return s.closeWithTimeout(timeout)
+}
+
+func (s *BoxService) closeWithTimeout(timeout time.Duration) error {
+	s.access.Lock()
+	defer s.access.Unlock()
+	if s.closed {
+		return os.ErrClosed
+	}
+	s.closed = true
+	s.trafficTracker.Close()
+	defer s.logManager.Close()
+	return s.closeInstance(timeout)
+}
+
+// Reload replaces the running instance with one built from configContent,
+// handing over the tun file descriptor and the default interface monitor so
+// the host does not have to reopen the VPN. If the new configuration cannot
+// be created the current instance is left untouched; if it cannot be started
+// the previous configuration is restored with its clash mode, selected
+// outbounds and URL test history, and the start error is returned. If the
+// current instance does not close in time the *CloseTimeoutError is returned
+// and the new configuration is not started. Reload and Close are serialized.
+func (s *BoxService) Reload(configContent string) error {
+	s.access.Lock()
+	defer s.access.Unlock()
+	if s.closed {
+		return os.ErrClosed
+	}
+	currentState := s.current()
+	newInstance, err := newService(configContent, s.platformInterface, s)
+	if err != nil {
+		return err
+	}
+	snapshot := currentState.snapshot()
+	interfaceListener := currentState.platformWrapper.interfaceListener
+	interfaceListener.hold()
+	defer interfaceListener.release()
+	err = currentState.close(C.FatalStopTimeout)
+	if IsCloseTimeout(err) {
+		newInstance.closeInstance(C.FatalStopTimeout)
+		return err
+	}
+	err = newInstance.Start()
+	if err == nil {
+		s.replace(newInstance)
+		return nil
+	}
+	newInstance.closeInstance(C.FatalStopTimeout)
+	previousInstance, restoreErr := newService(currentState.configContent, s.platformInterface, newInstance)
+	if restoreErr == nil {
+		restoreErr = previousInstance.Start()
+		if restoreErr != nil {
+			previousInstance.closeInstance(C.FatalStopTimeout)
+		} else {
+			previousInstance.current().restore(snapshot)
+		}
+	}
+	if restoreErr != nil {
+		return E.Errors(E.Cause(err, "start reloaded service"), E.Cause(restoreErr, "restore previous service"))
+	}
+	s.replace(previousInstance)
+	return E.Cause(err, "start reloaded service")
+}
+
+func (s *BoxService) replace(newInstance *BoxService) {
+	state := newInstance.current()
+	s.state.Store(state)
+	s.watchClashMode(state)
+	if s.commandServer != nil {
+		s.commandServer.SetService(s)
+	}
+}
+
+// serviceSnapshot is the runtime state of an instance that is not stored in
+// its configuration.
+type serviceSnapshot struct {
+	clashMode      string
+	selected       map[string]string
+	urlTestHistory map[string]*adapter.URLTestHistory
+}
+
+func (s *serviceState) snapshot() *serviceSnapshot {
+	snapshot := &serviceSnapshot{
+		selected:       make(map[string]string),
+		urlTestHistory: make(map[string]*adapter.URLTestHistory),
+	}
+	if s.clashServer != nil {
+		snapshot.clashMode = s.clashServer.Mode()
+	}
+	for _, outbound := range s.instance.Outbound().Outbounds() {
+		if selector, isSelector := outbound.(*group.Selector); isSelector {
+			snapshot.selected[selector.Tag()] = selector.Now()
+		}
+		if history := s.urlTestHistoryStorage.LoadURLTestHistory(outbound.Tag()); history != nil {
+			snapshot.urlTestHistory[outbound.Tag()] = history
+		}
+	}
+	return snapshot
+}
+
+func (s *serviceState) restore(snapshot *serviceSnapshot) {
+	for tag, history := range snapshot.urlTestHistory {
+		s.urlTestHistoryStorage.StoreURLTestHistory(tag, history)
+	}
+	for tag, selected := range snapshot.selected {
+		if outbound, loaded := s.instance.Outbound().Outbound(tag); loaded {
+			if selector, isSelector := outbound.(*group.Selector); isSelector {
+				selector.SelectOutbound(selected)
+			}
+		}
+	}
+	if clashServer, loaded := s.clashServer.(*clashapi.Server); loaded && snapshot.clashMode != "" {
+		clashServer.SetMode(snapshot.clashMode)
+	}
+}
+
+func (s *BoxService) closeInstance(timeout time.Duration) error {
+	return s.current().close(timeout)
+}
+
+func (s *serviceState) close(timeout time.Duration) error {
 	s.cancel()
 	s.urlTestHistoryStorage.Close()
 	var err error
 	done := make(chan struct{})
 	go func() {
+		defer close(done)
+		defer s.platformWrapper.untrackTuns()
+		defer catchPanic("close", s.configContent, &err)
 		err = s.instance.Close()
-		close(done)
 	}()
 	select {
 	case <-done:
 		return err
-	case <-time.After(C.FatalStopTimeout):
-		os.Exit(1)
-		return nil
+	case <-time.After(timeout):
+		timeoutErr := newCloseTimeoutError(timeout, done)
+		if sExitOnCloseTimeout {
+			writeCrashReport(&CrashReport{
+				Source:    "close",
+				Error:     timeoutErr.Error(),
+				Stack:     timeoutErr.GoroutineDump,
+				Version:   C.Version,
+				Timestamp: time.Now().UnixMilli(),
+			})
+			os.Exit(1)
+		}
+		return timeoutErr
 	}
 }
 
 func (s *BoxService) NeedWIFIState() bool {
-	return s.instance.Router().NeedWIFIState()
+	return s.current().instance.Router().NeedWIFIState()
 }
 
 func (s *BoxService) Pause() {
-	s.pauseManager.DevicePause()
+	s.current().pauseManager.DevicePause()
 }
 
 func (s *BoxService) Wake() {
-	s.pauseManager.DeviceWake()
+	s.current().pauseManager.DeviceWake()
 }
 
 var (
-	sBasePath        string
-	sWorkingPath     string
-	sTempPath        string
-	sUserID          int
-	sGroupID         int
-	sTVOS            bool
-	sFixAndroidStack bool
+	sBasePath           string
+	sWorkingPath        string
+	sTempPath           string
+	sUserID             int
+	sGroupID            int
+	sTVOS               bool
+	sFixAndroidStack    bool
+	sExitOnCloseTimeout bool
+	sNotifyDeprecated   bool
+	sAppVersion         string
 )
 
 func Setup(options *SetupOptions) error {
@@ -136,6 +353,9 @@ func Setup(options *SetupOptions) error {
 	sGroupID = os.Getgid()
 	sTVOS = options.IsTVOS
 	sFixAndroidStack = options.FixAndroidStack
+	sExitOnCloseTimeout = options.ExitOnCloseTimeout
+	sNotifyDeprecated = options.NotifyDeprecated
+	sAppVersion = options.AppVersion
 
 	os.MkdirAll(sWorkingPath, 0o700)
 	os.MkdirAll(sTempPath, 0o700)
@@ -143,12 +363,29 @@ func Setup(options *SetupOptions) error {
 	return nil
 }
 
+// SetExitOnCloseTimeout changes SetupOptions.ExitOnCloseTimeout after Setup.
+func SetExitOnCloseTimeout(enabled bool) {
+	sExitOnCloseTimeout = enabled
+}
+
 type SetupOptions struct {
 	BasePath        string
 	WorkingPath     string
 	TempPath        string
 	IsTVOS          bool
 	FixAndroidStack bool
+
+	// ExitOnCloseTimeout restores the behavior of exiting the process when
+	// closing the service exceeds its timeout, for hosts such as network
+	// extensions where a half-closed service must not linger.
+	ExitOnCloseTimeout bool
+
+	// NotifyDeprecated sends a notification through the platform interface
+	// the first time each app version uses a deprecated feature.
+	NotifyDeprecated bool
+	// AppVersion identifies the host app for NotifyDeprecated; the core
+	// version is used if it is empty.
+	AppVersion string
 }
 
 func SetMemoryLimit(enabled bool) {
@@ -191,7 +428,7 @@ func BaseContext(platformInterface PlatformInterface) context.Context {
 func parseConfig(ctx context.Context, configContent string) (option.Options, error) {
 	options, err := json.UnmarshalExtendedContext[option.Options](ctx, []byte(configContent))
 	if err != nil {
-		return option.Options{}, E.Cause(err, "parse config")
+		return option.Options{}, E.Cause(err, Current().ParseConfigMessage)
 	}
 	return options, nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,69 @@
+package liboc
+
+import (
+	"errors"
+	"runtime"
+	"time"
+
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+var ErrCloseTimeout = E.New("close timeout")
+
+// CloseTimeoutError is returned when closing a service exceeds its timeout.
+// GoroutineDump holds the stacks of all goroutines at that moment, which
+// shows the components still blocking in Close.
+type CloseTimeoutError struct {
+	Timeout       time.Duration
+	GoroutineDump string
+	done          <-chan struct{}
+}
+
+func newCloseTimeoutError(timeout time.Duration, done <-chan struct{}) *CloseTimeoutError {
+	buffer := make([]byte, 64*1024)
+	for {
+		n := runtime.Stack(buffer, true)
+		if n < len(buffer) {
+			buffer = buffer[:n]
+			break
+		}
+		buffer = make([]byte, 2*len(buffer))
+	}
+	return &CloseTimeoutError{
+		Timeout:       timeout,
+		GoroutineDump: string(buffer),
+		done:          done,
+	}
+}
+
+func (e *CloseTimeoutError) Error() string {
+	return "close service: timeout after " + e.Timeout.String()
+}
+
+func (e *CloseTimeoutError) Unwrap() error {
+	return ErrCloseTimeout
+}
+
+// Wait blocks until the close that timed out finishes in the background.
+// Resources the remaining components may still use, such as platform
+// callbacks, must be kept alive until then.
+func (e *CloseTimeoutError) Wait() {
+	if e.done != nil {
+		<-e.done
+	}
+}
+
+// IsCloseTimeout reports whether err is caused by a close timeout.
+func IsCloseTimeout(err error) bool {
+	return errors.Is(err, ErrCloseTimeout)
+}
+
+// CloseTimeoutGoroutineDump returns the goroutine dump attached to a close
+// timeout error, or an empty string for any other error.
+func CloseTimeoutGoroutineDump(err error) string {
+	var timeoutErr *CloseTimeoutError
+	if errors.As(err, &timeoutErr) {
+		return timeoutErr.GoroutineDump
+	}
+	return ""
+}
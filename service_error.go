@@ -5,36 +5,38 @@ import (
 	"path/filepath"
 )
 
-var serviceErrorPath string
-
-func init() {
-	if sWorkingPath != "" {
-		serviceErrorPath = filepath.Join(sWorkingPath, "service_error")
+func serviceErrorPath() string {
+	if sWorkingPath == "" {
+		return ""
 	}
+	return filepath.Join(sWorkingPath, "service_error")
 }
 
 func ClearServiceError() {
-	if serviceErrorPath == "" {
+	path := serviceErrorPath()
+	if path == "" {
 		return
 	}
-	os.Remove(serviceErrorPath)
+	os.Remove(path)
 }
 
 func ReadServiceError() (*StringBox, error) {
-	if serviceErrorPath == "" {
+	path := serviceErrorPath()
+	if path == "" {
 		return nil, os.ErrNotExist
 	}
-	content, err := os.ReadFile(serviceErrorPath)
+	content, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
-	os.Remove(serviceErrorPath)
+	os.Remove(path)
 	return &StringBox{Value: string(content)}, nil
 }
 
 func WriteServiceError(message string) error {
-	if serviceErrorPath == "" {
+	path := serviceErrorPath()
+	if path == "" {
 		return os.ErrInvalid
 	}
-	return os.WriteFile(serviceErrorPath, []byte(message), 0644)
-}
\ No newline at end of file
+	return os.WriteFile(path, []byte(message), 0644)
+}
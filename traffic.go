@@ -0,0 +1,222 @@
+package liboc
+
+import (
+	"context"
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing/common/bufio"
+	N "github.com/sagernet/sing/common/network"
+)
+
+var _ adapter.ConnectionTracker = (*trafficTracker)(nil)
+
+type TrafficSnapshot struct {
+	Timestamp     int64
+	Uplink        int64
+	Downlink      int64
+	UplinkTotal   int64
+	DownlinkTotal int64
+	outbounds     []*OutboundTraffic
+}
+
+func (t *TrafficSnapshot) Outbounds() OutboundTrafficIterator {
+	return newIterator(t.outbounds)
+}
+
+type OutboundTraffic struct {
+	Tag           string
+	Uplink        int64
+	Downlink      int64
+	UplinkTotal   int64
+	DownlinkTotal int64
+}
+
+type OutboundTrafficIterator interface {
+	Next() *OutboundTraffic
+	HasNext() bool
+}
+
+type TrafficListener interface {
+	WriteTraffic(snapshot *TrafficSnapshot)
+}
+
+type trafficCounter struct {
+	uplink   atomic.Int64
+	downlink atomic.Int64
+}
+
+// trafficTracker counts bytes per outbound. It is kept across reloads so
+// totals cover the whole lifetime of a BoxService.
+type trafficTracker struct {
+	ctx        context.Context
+	cancel     context.CancelFunc
+	access     sync.Mutex
+	total      trafficCounter
+	outbounds  map[string]*trafficCounter
+	lastSample *TrafficSnapshot
+	lastRate   *TrafficSnapshot
+}
+
+func newTrafficTracker() *trafficTracker {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &trafficTracker{
+		ctx:       ctx,
+		cancel:    cancel,
+		outbounds: make(map[string]*trafficCounter),
+	}
+}
+
+func (t *trafficTracker) Close() {
+	t.cancel()
+}
+
+func (t *trafficTracker) counters(outbound adapter.Outbound) ([]*atomic.Int64, []*atomic.Int64) {
+	t.access.Lock()
+	defer t.access.Unlock()
+	counter, loaded := t.outbounds[outbound.Tag()]
+	if !loaded {
+		counter = new(trafficCounter)
+		t.outbounds[outbound.Tag()] = counter
+	}
+	return []*atomic.Int64{&t.total.uplink, &counter.uplink}, []*atomic.Int64{&t.total.downlink, &counter.downlink}
+}
+
+func (t *trafficTracker) RoutedConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, matchedRule adapter.Rule, matchOutbound adapter.Outbound) net.Conn {
+	readCounter, writeCounter := t.counters(matchOutbound)
+	return bufio.NewInt64CounterConn(conn, readCounter, writeCounter)
+}
+
+func (t *trafficTracker) RoutedPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext, matchedRule adapter.Rule, matchOutbound adapter.Outbound) N.PacketConn {
+	readCounter, writeCounter := t.counters(matchOutbound)
+	return bufio.NewInt64CounterPacketConn(conn, readCounter, nil, writeCounter, nil)
+}
+
+// sample reads the current totals; rates are left zero.
+func (t *trafficTracker) sample() *TrafficSnapshot {
+	t.access.Lock()
+	defer t.access.Unlock()
+	snapshot := &TrafficSnapshot{
+		Timestamp:     time.Now().UnixMilli(),
+		UplinkTotal:   t.total.uplink.Load(),
+		DownlinkTotal: t.total.downlink.Load(),
+	}
+	for tag, counter := range t.outbounds {
+		snapshot.outbounds = append(snapshot.outbounds, &OutboundTraffic{
+			Tag:           tag,
+			UplinkTotal:   counter.uplink.Load(),
+			DownlinkTotal: counter.downlink.Load(),
+		})
+	}
+	sort.Slice(snapshot.outbounds, func(i, j int) bool {
+		return snapshot.outbounds[i].Tag < snapshot.outbounds[j].Tag
+	})
+	return snapshot
+}
+
+// Snapshot returns the current totals with per-second rates measured over
+// the last second or more.
+func (t *trafficTracker) Snapshot() *TrafficSnapshot {
+	snapshot := t.sample()
+	t.access.Lock()
+	defer t.access.Unlock()
+	if t.lastSample == nil {
+		t.lastSample = snapshot
+		return snapshot
+	}
+	if snapshot.Timestamp-t.lastSample.Timestamp >= int64(time.Second/time.Millisecond) {
+		fillTrafficRates(snapshot, t.lastSample)
+		t.lastSample = snapshot
+		t.lastRate = snapshot
+		return snapshot
+	}
+	if t.lastRate != nil {
+		copyTrafficRates(snapshot, t.lastRate)
+	}
+	return snapshot
+}
+
+func fillTrafficRates(snapshot *TrafficSnapshot, previous *TrafficSnapshot) {
+	elapsed := snapshot.Timestamp - previous.Timestamp
+	if elapsed <= 0 {
+		return
+	}
+	perSecond := func(current int64, last int64) int64 {
+		return (current - last) * 1000 / elapsed
+	}
+	snapshot.Uplink = perSecond(snapshot.UplinkTotal, previous.UplinkTotal)
+	snapshot.Downlink = perSecond(snapshot.DownlinkTotal, previous.DownlinkTotal)
+	previousOutbounds := make(map[string]*OutboundTraffic, len(previous.outbounds))
+	for _, outbound := range previous.outbounds {
+		previousOutbounds[outbound.Tag] = outbound
+	}
+	for _, outbound := range snapshot.outbounds {
+		var lastUplink, lastDownlink int64
+		if previousOutbound, loaded := previousOutbounds[outbound.Tag]; loaded {
+			lastUplink, lastDownlink = previousOutbound.UplinkTotal, previousOutbound.DownlinkTotal
+		}
+		outbound.Uplink = perSecond(outbound.UplinkTotal, lastUplink)
+		outbound.Downlink = perSecond(outbound.DownlinkTotal, lastDownlink)
+	}
+}
+
+func copyTrafficRates(snapshot *TrafficSnapshot, rated *TrafficSnapshot) {
+	snapshot.Uplink = rated.Uplink
+	snapshot.Downlink = rated.Downlink
+	ratedOutbounds := make(map[string]*OutboundTraffic, len(rated.outbounds))
+	for _, outbound := range rated.outbounds {
+		ratedOutbounds[outbound.Tag] = outbound
+	}
+	for _, outbound := range snapshot.outbounds {
+		if ratedOutbound, loaded := ratedOutbounds[outbound.Tag]; loaded {
+			outbound.Uplink = ratedOutbound.Uplink
+			outbound.Downlink = ratedOutbound.Downlink
+		}
+	}
+}
+
+type TrafficSubscription struct {
+	cancel context.CancelFunc
+}
+
+func (s *TrafficSubscription) Close() {
+	s.cancel()
+}
+
+// TrafficSnapshot returns total bytes and per-second rates, globally and per
+// outbound tag.
+func (s *BoxService) TrafficSnapshot() *TrafficSnapshot {
+	return s.trafficTracker.Snapshot()
+}
+
+// SubscribeTraffic pushes a snapshot to listener every intervalMs
+// milliseconds (one second if not positive) until the subscription or the
+// service is closed. Rates are measured over each interval.
+func (s *BoxService) SubscribeTraffic(listener TrafficListener, intervalMs int32) *TrafficSubscription {
+	interval := time.Second
+	if intervalMs > 0 {
+		interval = time.Duration(intervalMs) * time.Millisecond
+	}
+	ctx, cancel := context.WithCancel(s.trafficTracker.ctx)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		previous := s.trafficTracker.sample()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			snapshot := s.trafficTracker.sample()
+			fillTrafficRates(snapshot, previous)
+			previous = snapshot
+			listener.WriteTraffic(snapshot)
+		}
+	}()
+	return &TrafficSubscription{cancel}
+}
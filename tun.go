@@ -8,6 +8,8 @@ import (
 	"github.com/sagernet/sing-tun"
 	"github.com/sagernet/sing/common"
 	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+	"github.com/sagernet/sing/common/ranges"
 )
 
 type TunOptions interface {
@@ -28,6 +30,30 @@ type TunOptions interface {
 	GetHTTPProxyServerPort() int32
 	GetHTTPProxyBypassDomain() StringIterator
 	GetHTTPProxyMatchDomain() StringIterator
+	GetIncludePackage() StringIterator
+	GetExcludePackage() StringIterator
+	GetIncludeAndroidUser() Int32Iterator
+	GetIncludeUID() UIDRangeIterator
+	GetExcludeUID() UIDRangeIterator
+}
+
+type UIDRange struct {
+	Start int32
+	End   int32
+}
+
+type UIDRangeIterator interface {
+	Next() *UIDRange
+	HasNext() bool
+}
+
+func mapUIDRange(uidRanges []ranges.Range[uint32]) UIDRangeIterator {
+	return newIterator(common.Map(uidRanges, func(uidRange ranges.Range[uint32]) *UIDRange {
+		return &UIDRange{
+			Start: int32(uidRange.Start),
+			End:   int32(uidRange.End),
+		}
+	}))
 }
 
 type RoutePrefix struct {
@@ -91,7 +117,7 @@ func (o *tunOptions) GetInet6Address() RoutePrefixIterator {
 
 func (o *tunOptions) GetDNSServerAddress() (*StringBox, error) {
 	if len(o.Inet4Address) == 0 || o.Inet4Address[0].Bits() == 32 {
-		return nil, E.New("need one more IPv4 address for DNS hijacking")
+		return nil, E.New(Current().DNSServerAddressMessage)
 	}
 	return wrapString(o.Inet4Address[0].Addr().Next().String()), nil
 }
@@ -169,4 +195,68 @@ func (o *tunOptions) GetHTTPProxyMatchDomain() StringIterator {
 		return newIterator([]string{})
 	}
 	return newIterator(o.TunPlatformOptions.HTTPProxy.MatchDomain)
-}
\ No newline at end of file
+}
+
+func (o *tunOptions) GetIncludePackage() StringIterator {
+	return newIterator(o.IncludePackage)
+}
+
+func (o *tunOptions) GetExcludePackage() StringIterator {
+	return newIterator(o.ExcludePackage)
+}
+
+func (o *tunOptions) GetIncludeAndroidUser() Int32Iterator {
+	return newIterator(common.Map(o.IncludeAndroidUser, func(it int) int32 {
+		return int32(it)
+	}))
+}
+
+func (o *tunOptions) GetIncludeUID() UIDRangeIterator {
+	return mapUIDRange(o.IncludeUID)
+}
+
+func (o *tunOptions) GetExcludeUID() UIDRangeIterator {
+	return mapUIDRange(o.ExcludeUID)
+}
+
+// tunOptionsFingerprint covers everything the platform applies when opening
+// the tun, so a reload can reuse the existing descriptor only if the host
+// would have configured it identically.
+func tunOptionsFingerprint(options *tun.Options, routeRanges []netip.Prefix, platformOptions option.TunPlatformOptions) string {
+	content, _ := json.Marshal(struct {
+		Inet4Address             []netip.Prefix
+		Inet6Address             []netip.Prefix
+		MTU                      uint32
+		AutoRoute                bool
+		StrictRoute              bool
+		Inet4RouteAddress        []netip.Prefix
+		Inet6RouteAddress        []netip.Prefix
+		Inet4RouteExcludeAddress []netip.Prefix
+		Inet6RouteExcludeAddress []netip.Prefix
+		RouteRanges              []netip.Prefix
+		IncludePackage           []string
+		ExcludePackage           []string
+		IncludeAndroidUser       []int
+		IncludeUID               []ranges.Range[uint32]
+		ExcludeUID               []ranges.Range[uint32]
+		PlatformOptions          option.TunPlatformOptions
+	}{
+		options.Inet4Address,
+		options.Inet6Address,
+		options.MTU,
+		options.AutoRoute,
+		options.StrictRoute,
+		options.Inet4RouteAddress,
+		options.Inet6RouteAddress,
+		options.Inet4RouteExcludeAddress,
+		options.Inet6RouteExcludeAddress,
+		routeRanges,
+		options.IncludePackage,
+		options.ExcludePackage,
+		options.IncludeAndroidUser,
+		options.IncludeUID,
+		options.ExcludeUID,
+		platformOptions,
+	})
+	return string(content)
+}
@@ -0,0 +1,132 @@
+package liboc
+
+import (
+	"context"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/urltest"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/protocol/group"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/batch"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+type URLTestResult struct {
+	GroupTag    string
+	OutboundTag string
+	Delay       int32
+	Error       string
+	Time        int64
+}
+
+type URLTestListener interface {
+	WriteURLTestResult(result *URLTestResult)
+}
+
+// SetURLTestListener sets the listener receiving the result of every probe
+// started by URLTest and URLTestOutbound.
+func (s *BoxService) SetURLTestListener(listener URLTestListener) {
+	s.urlTestListener = listener
+}
+
+// URLTest probes every member of groupTag concurrently in the background.
+// Results are stored in the URL test history, so urltest groups switch to
+// the fastest member once all probes finish.
+func (s *BoxService) URLTest(groupTag string) error {
+	state := s.current()
+	abstractOutboundGroup, isLoaded := state.instance.Outbound().Outbound(groupTag)
+	if !isLoaded {
+		return E.New("outbound group not found: ", groupTag)
+	}
+	outboundGroup, isOutboundGroup := abstractOutboundGroup.(adapter.OutboundGroup)
+	if !isOutboundGroup {
+		return E.New("outbound is not a group: ", groupTag)
+	}
+	outbounds := common.Filter(common.Map(outboundGroup.All(), func(it string) adapter.Outbound {
+		itOutbound, _ := state.instance.Outbound().Outbound(it)
+		return itOutbound
+	}), func(it adapter.Outbound) bool {
+		if it == nil {
+			return false
+		}
+		_, isGroup := it.(adapter.OutboundGroup)
+		return !isGroup
+	})
+	go s.urlTest(state, groupTag, outbounds, "", C.TCPTimeout)
+	return nil
+}
+
+// URLTestOutbound probes a single outbound against link (the default test
+// URL if empty) in the background, giving up after timeoutMs milliseconds.
+func (s *BoxService) URLTestOutbound(tag string, link string, timeoutMs int32) error {
+	state := s.current()
+	outbound, isLoaded := state.instance.Outbound().Outbound(tag)
+	if !isLoaded {
+		return E.New("outbound not found: ", tag)
+	}
+	timeout := C.TCPTimeout
+	if timeoutMs > 0 {
+		timeout = time.Duration(timeoutMs) * time.Millisecond
+	}
+	go s.urlTest(state, "", []adapter.Outbound{outbound}, link, timeout)
+	return nil
+}
+
+func (s *BoxService) urlTest(state *serviceState, groupTag string, outbounds []adapter.Outbound, link string, timeout time.Duration) {
+	ctx := state.ctx
+	historyStorage := state.urlTestHistoryStorage
+	b, _ := batch.New(ctx, batch.WithConcurrencyNum[any](10))
+	for _, detour := range outbounds {
+		outboundToTest := detour
+		outboundTag := outboundToTest.Tag()
+		b.Go(outboundTag, func() (any, error) {
+			testCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			delay, err := urltest.URLTest(testCtx, link, outboundToTest)
+			result := &URLTestResult{
+				GroupTag:    groupTag,
+				OutboundTag: outboundTag,
+				Time:        time.Now().Unix(),
+			}
+			if err != nil {
+				historyStorage.DeleteURLTestHistory(outboundTag)
+				result.Error = err.Error()
+			} else {
+				historyStorage.StoreURLTestHistory(outboundTag, &adapter.URLTestHistory{
+					Time:  time.Now(),
+					Delay: delay,
+				})
+				result.Delay = int32(delay)
+			}
+			if listener := s.urlTestListener; listener != nil {
+				listener.WriteURLTestResult(result)
+			}
+			return nil, nil
+		})
+	}
+	b.Wait()
+	updateURLTestGroups(state, outbounds)
+}
+
+// updateURLTestGroups lets urltest groups containing a tested outbound
+// reselect. Their own check skips outbounds with fresh history, so this
+// only reevaluates the results stored above.
+func updateURLTestGroups(state *serviceState, testedOutbounds []adapter.Outbound) {
+	testedTags := make(map[string]bool, len(testedOutbounds))
+	for _, outbound := range testedOutbounds {
+		testedTags[outbound.Tag()] = true
+	}
+	for _, outbound := range state.instance.Outbound().Outbounds() {
+		urlTestGroup, isURLTest := outbound.(*group.URLTest)
+		if !isURLTest {
+			continue
+		}
+		if common.Any(urlTestGroup.All(), func(it string) bool {
+			return testedTags[it]
+		}) {
+			urlTestGroup.URLTest(state.ctx)
+		}
+	}
+}